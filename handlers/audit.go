@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boqier/kube-mcp-server/pkg/audit"
+	"github.com/boqier/kube-mcp-server/pkg/auth"
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/boqier/kube-mcp-server/pkg/policy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// auditManifestArguments 是包含manifest的参数，审计时只隐藏其中Secret的内容
+var auditManifestArguments = []string{"jsonManifest", "yamlManifest", "manifests"}
+
+const defaultAuditQueryLimit = 50
+
+// Auditor 把工具调用写入审计日志，默认只记录修改类的工具
+// 需要注册在CallerIdentities的中间件之后、PolicyEnforcer之前，这样被策略拒绝的调用也会被记录
+type Auditor struct {
+	logger   *audit.Logger
+	registry *k8s.Registry
+	allTools bool
+}
+
+// NewAuditor allTools为true时也记录只读的工具
+func NewAuditor(logger *audit.Logger, registry *k8s.Registry, allTools bool) *Auditor {
+	return &Auditor{logger: logger, registry: registry, allTools: allTools}
+}
+
+func (a *Auditor) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		verb, known := toolVerbs[tool]
+		// 不认识的工具也记录
+		readOnly := known && readVerbs[verb]
+		if readOnly && !a.allTools {
+			return next(ctx, request)
+		}
+
+		// 在调用之前取出目标，调用之后自动创建的命名空间已经存在
+		// manifest无法解析时调用本身也会失败，目标留空即可
+		targets, targetErr := toolTargets(ctx, a.registry, request)
+		start := time.Now()
+		result, err := next(ctx, request)
+		entry := audit.Entry{
+			Time:       start.UTC(),
+			Tool:       tool,
+			Verb:       verb,
+			ReadOnly:   readOnly,
+			Arguments:  audit.RedactArguments(request.GetArguments(), auditManifestArguments...),
+			Outcome:    audit.OutcomeSuccess,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			entry.Session = session.SessionID()
+		}
+		entry.User, entry.Groups = callerOf(ctx, a.registry)
+		if identity, ok := auth.IdentityFromContext(ctx); ok {
+			entry.Method = identity.Method
+		}
+		if targetErr == nil {
+			for _, target := range targets {
+				entry.Targets = append(entry.Targets, audit.Target(target))
+			}
+		}
+
+		var denied *policy.DeniedError
+		switch {
+		case errors.As(err, &denied):
+			entry.Outcome, entry.Error = audit.OutcomeDenied, err.Error()
+		case err != nil:
+			entry.Outcome, entry.Error = audit.OutcomeError, err.Error()
+		case result != nil && result.IsError:
+			entry.Outcome, entry.Error = audit.OutcomeError, resultText(result)
+		case isConfirmationPlan(result):
+			entry.Outcome = audit.OutcomePlanned
+		default:
+			entry.ResourceVersions = resultResourceVersions(result)
+		}
+		a.logger.Record(entry)
+		return result, err
+	}
+}
+
+// resultText 返回工具结果中的文本
+func resultText(result *mcp.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if textContent, ok := mcp.AsTextContent(content); ok {
+			text += textContent.Text
+		}
+	}
+	return text
+}
+
+// isConfirmationPlan 判断结果是否是Confirmations返回的计划
+func isConfirmationPlan(result *mcp.CallToolResult) bool {
+	if result == nil {
+		return false
+	}
+	var plan struct {
+		ConfirmationRequired bool `json:"confirmationRequired"`
+	}
+	return json.Unmarshal([]byte(resultText(result)), &plan) == nil && plan.ConfirmationRequired
+}
+
+// resultResourceVersions 从工具的JSON结果中取出修改后的resourceVersion：
+// 顶层的resourceVersion（apply）、对象的metadata.resourceVersion（rolloutRestart），以及results中每一项的
+func resultResourceVersions(result *mcp.CallToolResult) []string {
+	if result == nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(resultText(result)), &value); err != nil {
+		return nil
+	}
+	var versions []string
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if version, ok := obj["resourceVersion"].(string); ok && version != "" {
+			versions = append(versions, version)
+		} else if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if version, ok := metadata["resourceVersion"].(string); ok && version != "" {
+				versions = append(versions, version)
+			}
+		}
+		if items, ok := obj["results"].([]interface{}); ok {
+			for _, item := range items {
+				collect(item)
+			}
+		}
+	}
+	collect(value)
+	return versions
+}
+
+// parseAuditTime 解析查询的时间：today、24h或7d这样的时间段（从现在往前）、2006-01-02或RFC3339
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		year, month, day := now.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	window, err := parseWindow(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use today, a duration like 24h or 7d, a date like 2006-01-02 or an RFC3339 time", value)
+	}
+	return now.Add(-window), nil
+}
+
+// QueryAuditLog 按时间从新到旧返回审计日志中匹配的记录
+func QueryAuditLog(logger *audit.Logger) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		now := time.Now()
+		since, err := parseAuditTime(request.GetString("since", "today"), now)
+		if err != nil {
+			return nil, err
+		}
+		until, err := parseAuditTime(request.GetString("until", ""), now)
+		if err != nil {
+			return nil, err
+		}
+		filter := audit.Filter{
+			Since:       since,
+			Until:       until,
+			Tool:        request.GetString("tool", ""),
+			User:        request.GetString("user", ""),
+			Session:     request.GetString("session", ""),
+			Outcome:     request.GetString("outcome", ""),
+			Kind:        request.GetString("kind", ""),
+			Name:        request.GetString("name", ""),
+			Namespace:   request.GetString("namespace", ""),
+			Cluster:     request.GetString("cluster", ""),
+			ChangesOnly: request.GetBool("changesOnly", true),
+			Limit:       request.GetInt("limit", defaultAuditQueryLimit),
+		}
+		entries, err := logger.Query(filter)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(map[string]interface{}{
+			"count":   len(entries),
+			"entries": entries,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// confirmTools 是开启确认模式后需要确认才会执行的工具
+var confirmTools = map[string]bool{
+	"deleteResource":     true,
+	"rolloutRestart":     true,
+	"createResourceJSON": true,
+	"createResourceYAML": true,
+	"applyManifests":     true,
+}
+
+const confirmationTokenArgument = "confirmationToken"
+
+// pendingConfirmation 是已经返回给调用方、还没有被使用的确认token
+type pendingConfirmation struct {
+	tool        string
+	fingerprint string
+	user        string
+	session     string
+	expires     time.Time
+}
+
+// Confirmations 让修改类的工具分两步执行：第一次调用只返回计划（要修改的对象和dry-run的结果）和一个短期有效的token，
+// 带着token用相同的参数再次调用才会真正执行；客户端支持elicitation时直接询问用户，不需要第二次调用
+// 需要注册在PolicyEnforcer的中间件之后，被策略拒绝的调用不会生成计划
+type Confirmations struct {
+	registry *k8s.Registry
+	ttl      time.Duration
+	lock     sync.Mutex
+	pending  map[string]pendingConfirmation
+}
+
+// NewConfirmations ttl是token的有效期
+func NewConfirmations(registry *k8s.Registry, ttl time.Duration) *Confirmations {
+	return &Confirmations{
+		registry: registry,
+		ttl:      ttl,
+		pending:  make(map[string]pendingConfirmation),
+	}
+}
+
+func (c *Confirmations) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		// dry-run本身不修改任何东西
+		if !confirmTools[tool] || request.GetBool("dryRun", false) {
+			return next(ctx, request)
+		}
+		if token := request.GetString(confirmationTokenArgument, ""); token != "" {
+			if err := c.redeem(ctx, token, request); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+
+		plan, err := c.plan(ctx, request, next)
+		if err != nil {
+			return nil, err
+		}
+		if plan.result != nil {
+			// dry-run失败，真正执行也会失败，直接返回它的错误
+			return plan.result, nil
+		}
+		if session, ok := elicitationSession(ctx); ok {
+			confirmed, err := elicitConfirmation(ctx, session, plan)
+			if err == nil {
+				if !confirmed {
+					return nil, fmt.Errorf("the user did not confirm %s, nothing was changed", tool)
+				}
+				return next(ctx, request)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: failed to ask the user to confirm %s, returning a confirmation token instead: %v\n", tool, err)
+		}
+
+		token, expires, err := c.issue(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(map[string]interface{}{
+			"confirmationRequired": true,
+			"message": fmt.Sprintf("Nothing was changed. Show this plan to the user; only if they approve it, call %s again with exactly the same arguments and confirmationToken before %s.",
+				tool, expires.UTC().Format(time.RFC3339)),
+			"confirmationToken": token,
+			"expiresAt":         expires.UTC().Format(time.RFC3339),
+			"verb":              toolVerbs[tool],
+			"targets":           plan.targets,
+			"dryRun":            plan.dryRun,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// changePlan 是第一次调用返回的计划，result不为空时表示dry-run返回了错误结果
+type changePlan struct {
+	tool    string
+	targets []callTarget
+	dryRun  interface{}
+	result  *mcp.CallToolResult
+}
+
+// plan 用dryRun=true调用处理函数，得到API Server计算出的结果
+func (c *Confirmations) plan(ctx context.Context, request mcp.CallToolRequest, next server.ToolHandlerFunc) (*changePlan, error) {
+	targets, err := toolTargets(ctx, c.registry, request)
+	if err != nil {
+		return nil, err
+	}
+	arguments := make(map[string]interface{}, len(request.GetArguments())+1)
+	for name, value := range request.GetArguments() {
+		arguments[name] = value
+	}
+	arguments["dryRun"] = true
+	dryRunRequest := request
+	dryRunRequest.Params.Arguments = arguments
+	result, err := next(ctx, dryRunRequest)
+	if err != nil {
+		return nil, fmt.Errorf("dry run of %s failed, nothing was changed: %w", request.Params.Name, err)
+	}
+	plan := &changePlan{tool: request.Params.Name, targets: targets}
+	if result == nil {
+		return plan, nil
+	}
+	if result.IsError {
+		plan.result = result
+		return plan, nil
+	}
+	text := resultText(result)
+	var dryRun interface{}
+	if err := json.Unmarshal([]byte(text), &dryRun); err == nil {
+		plan.dryRun = dryRun
+	} else {
+		plan.dryRun = text
+	}
+	return plan, nil
+}
+
+// fingerprint 是工具和参数（不包括token和dryRun）的摘要，token只能用于和计划完全相同的调用
+func fingerprint(request mcp.CallToolRequest) (string, error) {
+	arguments := make(map[string]interface{}, len(request.GetArguments()))
+	for name, value := range request.GetArguments() {
+		if name != confirmationTokenArgument && name != "dryRun" {
+			arguments[name] = value
+		}
+	}
+	// map序列化时按键排序，结果是确定的
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize arguments: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(request.Params.Name+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func sessionIDOf(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// issue 生成一个token，同时清理过期的token
+func (c *Confirmations) issue(ctx context.Context, request mcp.CallToolRequest) (string, time.Time, error) {
+	digest, err := fingerprint(request)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(random)
+	user, _ := callerOf(ctx, c.registry)
+	now := time.Now()
+	expires := now.Add(c.ttl)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, pending := range c.pending {
+		if now.After(pending.expires) {
+			delete(c.pending, key)
+		}
+	}
+	c.pending[token] = pendingConfirmation{
+		tool:        request.Params.Name,
+		fingerprint: digest,
+		user:        user,
+		session:     sessionIDOf(ctx),
+		expires:     expires,
+	}
+	return token, expires, nil
+}
+
+// redeem 检查token属于同一个调用方和会话、没有过期并且参数没有变化，token只能使用一次
+func (c *Confirmations) redeem(ctx context.Context, token string, request mcp.CallToolRequest) error {
+	digest, err := fingerprint(request)
+	if err != nil {
+		return err
+	}
+	user, _ := callerOf(ctx, c.registry)
+
+	c.lock.Lock()
+	pending, ok := c.pending[token]
+	if ok {
+		delete(c.pending, token)
+	}
+	c.lock.Unlock()
+
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown or already used confirmation token, call %s without confirmationToken to get a new plan", request.Params.Name)
+	case time.Now().After(pending.expires):
+		return fmt.Errorf("confirmation token expired at %s, call %s without confirmationToken to get a new plan", pending.expires.UTC().Format(time.RFC3339), request.Params.Name)
+	case pending.user != user || pending.session != sessionIDOf(ctx):
+		return fmt.Errorf("confirmation token was issued to another caller")
+	case pending.tool != request.Params.Name || subtle.ConstantTimeCompare([]byte(pending.fingerprint), []byte(digest)) != 1:
+		return fmt.Errorf("confirmation token was issued for a different call, the arguments must be exactly the same as in the plan")
+	}
+	return nil
+}
+
+// elicitationSession 返回支持elicitation的会话，客户端需要在初始化时声明这个能力
+func elicitationSession(ctx context.Context) (server.SessionWithElicitation, bool) {
+	session := server.ClientSessionFromContext(ctx)
+	elicitation, ok := session.(server.SessionWithElicitation)
+	if !ok {
+		return nil, false
+	}
+	info, ok := session.(server.SessionWithClientInfo)
+	if !ok || info.GetClientCapabilities().Elicitation == nil {
+		return nil, false
+	}
+	return elicitation, true
+}
+
+// elicitConfirmation 把计划展示给用户，用户接受并勾选确认时返回true
+func elicitConfirmation(ctx context.Context, session server.SessionWithElicitation, plan *changePlan) (bool, error) {
+	result, err := session.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: planMessage(plan),
+			RequestedSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"confirm": map[string]interface{}{
+						"type":        "boolean",
+						"title":       "Apply this change",
+						"description": "The change is only applied when this is checked",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return false, nil
+	}
+	content, _ := result.Content.(map[string]interface{})
+	confirmed, _ := content["confirm"].(bool)
+	return confirmed, nil
+}
+
+// planMessage 是给用户看的计划摘要，列出要修改的每个对象
+func planMessage(plan *changePlan) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "An agent wants to call %s (%s) on %d object(s):\n", plan.tool, toolVerbs[plan.tool], len(plan.targets))
+	for _, target := range plan.targets {
+		object := target.Kind + " " + target.Name
+		if target.Namespace != "" {
+			object = target.Kind + " " + target.Namespace + "/" + target.Name
+		}
+		fmt.Fprintf(&message, "- %s in cluster %s\n", object, target.Cluster)
+	}
+	if dryRun, ok := plan.dryRun.(map[string]interface{}); ok {
+		if dependents := countDependents(dryRun["dependents"]); dependents > 0 {
+			fmt.Fprintf(&message, "%d dependent object(s) will be deleted by the garbage collector as well.\n", dependents)
+		}
+		if contents, ok := dryRun["namespaceContents"].(map[string]interface{}); ok && len(contents) > 0 {
+			fmt.Fprintf(&message, "All objects in the namespace will be deleted: %s.\n", countsText(contents))
+		}
+		if failures, ok := dryRun["dependentsIncomplete"].([]interface{}); ok && len(failures) > 0 {
+			fmt.Fprintf(&message, "Some dependents are unknown, %d resource type(s) could not be listed with your permissions.\n", len(failures))
+		}
+	}
+	message.WriteString("The dry run on the API server succeeded. Do you want to apply this change?")
+	return message.String()
+}
+
+// countsText 把按kind统计的数量排序后拼接成 "3 ConfigMap, 2 Pod"
+func countsText(counts map[string]interface{}) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		count, _ := counts[kind].(float64)
+		parts = append(parts, fmt.Sprintf("%d %s", int(count), kind))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countDependents 统计DeleteResource返回的子对象树中的对象数量，截断的部分也计算在内
+func countDependents(value interface{}) int {
+	items, _ := value.([]interface{})
+	count := 0
+	for _, item := range items {
+		entry, _ := item.(map[string]interface{})
+		if truncated, ok := entry["truncated"].(float64); ok {
+			count += int(truncated)
+			continue
+		}
+		count += 1 + countDependents(entry["children"])
+	}
+	return count
+}
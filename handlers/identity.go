@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/boqier/kube-mcp-server/pkg/auth"
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CallerIdentities 决定每次调用以哪个Kubernetes身份执行，优先级从高到低：
+// - 当前HTTP请求认证得到的身份（需要启用impersonateAuthenticated）
+// - 当前HTTP请求头中的身份（sse和streamable-http模式，需要配置头的名称）
+// - 会话初始化时记录的身份，之后的请求不带头时沿用它
+// - Registry上配置的默认身份
+// 请求头可以被调用方任意伪造，只应该在前面有负责认证的代理、并且代理会覆盖这些头时启用
+type CallerIdentities struct {
+	userHeader               string
+	groupHeader              string
+	impersonateAuthenticated bool
+	lock                     sync.Mutex
+	sessions                 map[string]k8s.Identity
+}
+
+// NewCallerIdentities userHeader为空时不从请求头读取身份
+func NewCallerIdentities(userHeader, groupHeader string, impersonateAuthenticated bool) *CallerIdentities {
+	return &CallerIdentities{
+		userHeader:               userHeader,
+		groupHeader:              groupHeader,
+		impersonateAuthenticated: impersonateAuthenticated,
+		sessions:                 make(map[string]k8s.Identity),
+	}
+}
+
+// HTTPContextFunc 把认证得到的身份或请求头中的身份放入context，同时适用于WithSSEContextFunc和WithHTTPContextFunc
+// 组可以重复同一个头，也可以用逗号分隔
+func (c *CallerIdentities) HTTPContextFunc(ctx context.Context, r *http.Request) context.Context {
+	if authenticated, ok := auth.IdentityFromContext(ctx); ok && c.impersonateAuthenticated {
+		return k8s.WithIdentity(ctx, k8s.Identity{User: authenticated.User, Groups: authenticated.Groups})
+	}
+	if c.userHeader == "" {
+		return ctx
+	}
+	identity := k8s.Identity{User: strings.TrimSpace(r.Header.Get(c.userHeader))}
+	if identity.User == "" {
+		return ctx
+	}
+	if c.groupHeader != "" {
+		for _, value := range r.Header.Values(c.groupHeader) {
+			identity.Groups = append(identity.Groups, splitComma(value)...)
+		}
+	}
+	return k8s.WithIdentity(ctx, identity)
+}
+
+func splitComma(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RememberSession 是AfterInitialize钩子，记录会话初始化请求中的身份
+func (c *CallerIdentities) RememberSession(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+	identity, ok := k8s.IdentityFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	if !ok || session == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sessions[session.SessionID()] = identity
+}
+
+// RemoveSession 在会话断开时调用
+func (c *CallerIdentities) RemoveSession(sessionID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.sessions, sessionID)
+}
+
+// withSessionIdentity 请求本身没有身份时使用会话记录的身份
+func (c *CallerIdentities) withSessionIdentity(ctx context.Context) context.Context {
+	if _, ok := k8s.IdentityFromContext(ctx); ok {
+		return ctx
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return ctx
+	}
+	c.lock.Lock()
+	identity, ok := c.sessions[session.SessionID()]
+	c.lock.Unlock()
+	if !ok {
+		return ctx
+	}
+	return k8s.WithIdentity(ctx, identity)
+}
+
+// ToolMiddleware 让工具处理函数通过Registry.ClientFor拿到调用方身份的客户端
+func (c *CallerIdentities) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(c.withSessionIdentity(ctx), request)
+	}
+}
+
+// ResourceMiddleware 和ToolMiddleware相同，作用于资源读取
+func (c *CallerIdentities) ResourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return next(c.withSessionIdentity(ctx), request)
+	}
+}
+
+// WhoAmI 返回调用方认证得到的身份，以及访问Kubernetes时使用的身份
+func WhoAmI(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result := map[string]interface{}{
+			"authenticated": nil,
+			"kubernetes":    "the server's own identity",
+		}
+		if identity, ok := auth.IdentityFromContext(ctx); ok {
+			result["authenticated"] = identity
+		}
+		if identity, ok := k8s.IdentityFromContext(ctx); ok {
+			result["kubernetes"] = identity
+		} else if identity := registry.DefaultIdentity(); !identity.IsZero() {
+			result["kubernetes"] = identity
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
@@ -4,13 +4,82 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/boqier/kube-mcp-server/pkg/k8s"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
-func GetAPIResources(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// listResources 默认每页返回的数量，避免一次返回过多对象
+const defaultListLimit = 200
+
+// clientFromRequest 根据请求中的cluster参数从registry中取出对应集群的客户端，未指定时使用默认集群
+func clientFromRequest(ctx context.Context, registry *k8s.Registry, request mcp.CallToolRequest) (*k8s.Client, error) {
+	cluster := request.GetString("cluster", "")
+	client, err := registry.ClientFor(ctx, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client for cluster %q: %w", cluster, err)
+	}
+	return client, nil
+}
+
+// applyOptionsFromRequest 读取apply类工具共用的dryRun、force和fieldManager参数
+func applyOptionsFromRequest(request mcp.CallToolRequest) k8s.ApplyOptions {
+	return k8s.ApplyOptions{
+		DryRun:       request.GetBool("dryRun", false),
+		Force:        request.GetBool("force", false),
+		FieldManager: request.GetString("fieldManager", k8s.DefaultFieldManager),
+	}
+}
+
+// projectionFromRequest 读取getResource、describeResource和listResources共用的fields、jsonPath和stripManaged参数
+func projectionFromRequest(request mcp.CallToolRequest) (k8s.Projection, error) {
+	projection := k8s.Projection{
+		Fields:       request.GetStringSlice("fields", nil),
+		JSONPath:     request.GetString("jsonPath", ""),
+		StripManaged: request.GetBool("stripManaged", true),
+	}
+	if len(projection.Fields) > 0 && projection.JSONPath != "" {
+		return projection, fmt.Errorf("fields and jsonPath can not be used together")
+	}
+	return projection, nil
+}
+
+// projectedResult 把投影结果转换为工具的返回值，jsonPath渲染出的文本直接返回
+func projectedResult(projection k8s.Projection, obj map[string]interface{}) (*mcp.CallToolResult, error) {
+	projected, err := projection.Apply(obj)
+	if err != nil {
+		return nil, err
+	}
+	if text, ok := projected.(string); ok {
+		return mcp.NewToolResultText(text), nil
+	}
+	jsonResponse, err := json.Marshal(projected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize response:%w", err)
+	}
+	return mcp.NewToolResultText(string(jsonResponse)), nil
+}
+
+func ListClusters(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		jsonResponse, err := json.Marshal(registry.ListClusters())
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func GetAPIResources(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		includeNamespaceScoped := request.GetBool("includeNamespaceScoped", true)
 		includeClusterScope := request.GetBool("includeClusterScoped", true)
 		//获取资源清单
@@ -26,30 +95,38 @@ func GetAPIResources(client *k8s.Client) func(ctx context.Context, request mcp.C
 		return mcp.NewToolResultText(string(jsonResponse)), nil
 	}
 }
-func GetResources(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func GetResources(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		kind := request.GetString("kind", "")
 
 		name := request.GetString("name", "")
 
 		namespace := request.GetString("namespace", "")
 
+		projection, err := projectionFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
 		//获取资源清单
 		resource, err := client.GetResource(ctx, kind, name, namespace)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get resource:%w", err)
 		}
 
-		//转为json
-		jsonResponse, err := json.Marshal(resource)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize response:%w", err)
-		}
-		return mcp.NewToolResultText(string(jsonResponse)), nil
+		return projectedResult(projection, resource)
 	}
 }
-func ListResources(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func ListResources(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		kind, err := request.RequireString("kind")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get kind:%w", err)
@@ -61,8 +138,20 @@ func ListResources(client *k8s.Client) func(ctx context.Context, request mcp.Cal
 
 		fieldSelector := request.GetString("fieldSelector", "")
 
+		page := k8s.ListPage{
+			Limit:    int64(request.GetInt("limit", defaultListLimit)),
+			Continue: request.GetString("continue", ""),
+		}
+		if page.Limit < 0 {
+			return nil, fmt.Errorf("limit must not be negative")
+		}
+		projection, err := projectionFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
 		//获取资源清单
-		resources, err := client.ListResources(ctx, kind, namespace, labelSelector, fieldSelector)
+		resources, err := client.ListResources(ctx, kind, namespace, labelSelector, fieldSelector, page, projection)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list resources:%w", err)
 		}
@@ -76,8 +165,12 @@ func ListResources(client *k8s.Client) func(ctx context.Context, request mcp.Cal
 	}
 }
 
-func CreateOrUpdateResourceYAML(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func CreateOrUpdateResourceYAML(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		yamlManifest, err := request.RequireString("yamlManifest")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get yamlManifest:%w", err)
@@ -86,7 +179,7 @@ func CreateOrUpdateResourceYAML(client *k8s.Client) func(ctx context.Context, re
 		kind := request.GetString("kind", "")
 
 		//创建或更新资源
-		resource, err := client.CreateOrUpdateResourceYAML(ctx, namespace, yamlManifest, kind)
+		resource, err := client.CreateOrUpdateResourceYAML(ctx, namespace, yamlManifest, kind, applyOptionsFromRequest(request))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create or update resource:%w", err)
 		}
@@ -99,8 +192,12 @@ func CreateOrUpdateResourceYAML(client *k8s.Client) func(ctx context.Context, re
 	}
 }
 
-func CreateOrUpdateResourceJSON(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func CreateOrUpdateResourceJSON(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		jsonManifest, err := request.RequireString("jsonManifest")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get jsonManifest:%w", err)
@@ -109,7 +206,7 @@ func CreateOrUpdateResourceJSON(client *k8s.Client) func(ctx context.Context, re
 		kind := request.GetString("kind", "")
 
 		//创建或更新资源
-		resource, err := client.CreateOrUpdateResoureceJSON(ctx, namespace, jsonManifest, kind)
+		resource, err := client.CreateOrUpdateResoureceJSON(ctx, namespace, jsonManifest, kind, applyOptionsFromRequest(request))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create or update resource:%w", err)
 		}
@@ -122,8 +219,37 @@ func CreateOrUpdateResourceJSON(client *k8s.Client) func(ctx context.Context, re
 	}
 }
 
-func DeleteResource(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func ApplyManifests(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		manifests, err := request.RequireString("manifests")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get manifests:%w", err)
+		}
+		namespace := request.GetString("namespace", "")
+		continueOnError := request.GetBool("continueOnError", false)
+
+		result, err := client.ApplyManifests(ctx, namespace, manifests, continueOnError, applyOptionsFromRequest(request))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply manifests:%w", err)
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func DeleteResource(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		namespace := request.GetString("namespace", "")
 		name, err := request.RequireString("name")
 		if err != nil {
@@ -133,16 +259,28 @@ func DeleteResource(client *k8s.Client) func(ctx context.Context, request mcp.Ca
 		if err != nil {
 			return nil, fmt.Errorf("kind is require!%w", err)
 		}
-		err = client.DeleteResource(ctx, kind, name, namespace)
+		dryRun := request.GetBool("dryRun", false)
+		result, err := client.DeleteResource(ctx, kind, name, namespace, dryRun)
 		if err != nil {
 			return nil, fmt.Errorf("delete resource failed:%w", err)
 		}
-		return mcp.NewToolResultText("Rrsource deleted successfully"), nil
+		if !dryRun {
+			return mcp.NewToolResultText("Rrsource deleted successfully"), nil
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
 	}
 }
 
-func DescribeResources(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func DescribeResources(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 
 		kind, err := request.RequireString("kind")
 		if err != nil {
@@ -156,33 +294,54 @@ func DescribeResources(client *k8s.Client) func(ctx context.Context, request mcp
 
 		namespace := request.GetString("namespace", "")
 
+		projection, err := projectionFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
 		// Fetch resource description
 		resourceDescription, err := client.DescribeResource(ctx, kind, name, namespace)
 		if err != nil {
 			return nil, fmt.Errorf("failed to describe resource '%s' of kind '%s': %w", name, kind, err)
 		}
 
-		// Serialize response to JSON
-		jsonResponse, err := json.Marshal(resourceDescription)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize response: %w", err)
-		}
+		return projectedResult(projection, resourceDescription)
+	}
+}
 
-		// Return JSON response using NewToolResultText
-		return mcp.NewToolResultText(string(jsonResponse)), nil
+// podLogOptionsFromRequest 读取日志工具共用的参数，没有grep时默认只读最后100行，有grep时默认搜索全部日志
+func podLogOptionsFromRequest(request mcp.CallToolRequest) k8s.PodLogOptions {
+	grep := request.GetString("grep", "")
+	defaultTail := 100
+	if grep != "" {
+		defaultTail = 0
+	}
+	return k8s.PodLogOptions{
+		TailLines:    int64(request.GetInt("TailLogsLen", defaultTail)),
+		Previous:     request.GetBool("previous", false),
+		SinceSeconds: int64(request.GetInt("sinceSeconds", 0)),
+		SinceTime:    request.GetString("sinceTime", ""),
+		Timestamps:   request.GetBool("timestamps", false),
+		Grep:         grep,
+		ContextLines: request.GetInt("contextLines", 0),
+		MaxBytes:     int64(request.GetInt("maxBytes", k8s.DefaultLogBytes)),
 	}
 }
 
-func GetPodsLogs(client k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func GetPodsLogs(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		TailLogsLen := request.GetInt("TailLogsLen", 100)
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		name, err := request.RequireString("Name")
 		if err != nil {
 			return nil, fmt.Errorf("name is require!:%w", err)
 		}
 		namespace := request.GetString("namespace", "default")
-		containerName := request.GetString("containerName", "")
-		Logs, err := client.GetPodsLogs(ctx, namespace, containerName, name, TailLogsLen)
+		options := podLogOptionsFromRequest(request)
+		options.Container = request.GetString("containerName", "")
+		Logs, err := client.GetPodsLogs(ctx, namespace, name, options)
 		if err != nil {
 			return nil, err
 		}
@@ -191,8 +350,57 @@ func GetPodsLogs(client k8s.Client) func(ctx context.Context, request mcp.CallTo
 	}
 }
 
-func GetPodMetrics(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func GetWorkloadLogs(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		target := k8s.WorkloadLogTarget{
+			Kind:          request.GetString("kind", ""),
+			Name:          request.GetString("name", ""),
+			LabelSelector: request.GetString("labelSelector", ""),
+		}
+		namespace := request.GetString("namespace", "default")
+		options := podLogOptionsFromRequest(request)
+		options.Container = request.GetString("containerName", "")
+		logs, err := client.GetWorkloadLogs(ctx, namespace, target, options)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(logs), nil
+	}
+}
+
+func DiagnosePod(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, err
+		}
+		namespace := request.GetString("namespace", "default")
+		diagnosis, err := client.DiagnosePod(ctx, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diagnose pod '%s' in namespace '%s': %w", name, namespace, err)
+		}
+		jsonResponse, err := json.Marshal(diagnosis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize diagnosis: %w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func GetPodMetrics(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		namespace, err := request.RequireString("namespace")
 		if err != nil {
 			return nil, fmt.Errorf("namespace is required")
@@ -215,11 +423,16 @@ func GetPodMetrics(client *k8s.Client) func(ctx context.Context, request mcp.Cal
 	}
 }
 
-func GetNodeMetrics(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func GetNodeMetrics(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		nodeName, err := request.RequireString("podName")
+		client, err := clientFromRequest(ctx, registry, request)
 		if err != nil {
-			return nil, fmt.Errorf("nodeName is required:%w", &err)
+			return nil, err
+		}
+		// 旧版本的工具使用podName作为节点名称参数
+		nodeName := request.GetString("nodeName", request.GetString("podName", ""))
+		if nodeName == "" {
+			return nil, fmt.Errorf("nodeName is required")
 		}
 		result, err := client.GetNodeMetrics(ctx, nodeName)
 		if err != nil {
@@ -233,8 +446,179 @@ func GetNodeMetrics(client *k8s.Client) func(ctx context.Context, request mcp.Ca
 	}
 }
 
-func GetEvents(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// topOptionsFromRequest 读取topPods和topNodes共用的参数
+func topOptionsFromRequest(request mcp.CallToolRequest) k8s.TopOptions {
+	return k8s.TopOptions{
+		Namespace:     request.GetString("namespace", ""),
+		LabelSelector: request.GetString("labelSelector", ""),
+		SortBy:        request.GetString("sortBy", k8s.TopSortCPU),
+		Limit:         request.GetInt("limit", k8s.DefaultTopLimit),
+	}
+}
+
+func TopPods(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.TopPods(ctx, topOptionsFromRequest(request))
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func TopNodes(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.TopNodes(ctx, topOptionsFromRequest(request))
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// RightsizingReport 中history是Prometheus客户端，为nil时只使用metrics-server
+// Prometheus只对应默认集群，查询其他集群时不使用历史数据
+func RightsizingReport(registry *k8s.Registry, history k8s.UsageHistory) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, err
+		}
+		window := k8s.DefaultRightsizingWindow
+		if value := request.GetString("window", ""); value != "" {
+			window, err = parseWindow(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		opts := k8s.RightsizingOptions{
+			Namespace: namespace,
+			Kind:      request.GetString("kind", ""),
+			Name:      request.GetString("name", ""),
+			Window:    window,
+		}
+		cluster := request.GetString("cluster", "")
+		if cluster == "" || cluster == registry.DefaultCluster() {
+			opts.History = history
+		}
+		report, err := client.RightsizingReport(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// parseWindow 解析时间窗口，除了Go的duration格式还支持按天表示，例如7d
+func parseWindow(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid window %q, use a duration like 24h or 7d", value)
+	}
+	return window, nil
+}
+
+// accessCheckFromRequest 读取canI和whoCan共用的参数
+func accessCheckFromRequest(request mcp.CallToolRequest) (k8s.AccessCheck, error) {
+	verb, err := request.RequireString("verb")
+	if err != nil {
+		return k8s.AccessCheck{}, err
+	}
+	kind, err := request.RequireString("kind")
+	if err != nil {
+		return k8s.AccessCheck{}, err
+	}
+	return k8s.AccessCheck{
+		Verb:      verb,
+		Kind:      kind,
+		Name:      request.GetString("name", ""),
+		Namespace: request.GetString("namespace", ""),
+		User:      request.GetString("user", ""),
+		Groups:    request.GetStringSlice("groups", nil),
+	}, nil
+}
+
+func CanI(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		check, err := accessCheckFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.CanI(ctx, check)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func WhoCan(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		check, err := accessCheckFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := client.WhoCan(ctx, check)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func GetEvents(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 
 		namespace := request.GetString("namespace", "")
 		labelSelector := request.GetString("labelSelector", "")
@@ -252,8 +636,12 @@ func GetEvents(client *k8s.Client) func(ctx context.Context, request mcp.CallToo
 	}
 }
 
-func GetIngresses(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func GetIngresses(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		host := request.GetString("host", "")
 
 		ingresses, err := client.GetIngresses(ctx, host)
@@ -270,8 +658,12 @@ func GetIngresses(client *k8s.Client) func(ctx context.Context, request mcp.Call
 	}
 }
 
-func RolloutRestart(client *k8s.Client) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func RolloutRestart(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
 		kind, err := request.RequireString("kind")
 		if err != nil {
 			return nil, fmt.Errorf("required kind")
@@ -284,7 +676,7 @@ func RolloutRestart(client *k8s.Client) func(ctx context.Context, request mcp.Ca
 		if err != nil {
 			return nil, fmt.Errorf("required namespace")
 		}
-		result, err := client.RolloutRestart(ctx, kind, name, namespace)
+		result, err := client.RolloutRestart(ctx, kind, name, namespace, request.GetBool("dryRun", false))
 		if err != nil {
 			return nil, fmt.Errorf("failed to rollout restart resource: %w", err)
 		}
@@ -297,3 +689,277 @@ func RolloutRestart(client *k8s.Client) func(ctx context.Context, request mcp.Ca
 		return mcp.NewToolResultText(string(jsonResponse)), nil
 	}
 }
+
+func ScaleResource(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := request.RequireString("kind")
+		if err != nil {
+			return nil, fmt.Errorf("required kind")
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("required namespace")
+		}
+		replicas, err := request.RequireString("replicas")
+		if err != nil {
+			return nil, fmt.Errorf("required replicas")
+		}
+		wait := request.GetBool("wait", false)
+		timeout := time.Duration(request.GetInt("timeoutSeconds", 300)) * time.Second
+
+		result, err := client.ScaleResource(ctx, kind, name, namespace, replicas, wait, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scale resource: %w", err)
+		}
+
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func RolloutStatus(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := request.RequireString("kind")
+		if err != nil {
+			return nil, fmt.Errorf("required kind")
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("required namespace")
+		}
+		timeout := time.Duration(request.GetInt("timeoutSeconds", 60)) * time.Second
+
+		result, err := client.RolloutStatus(ctx, kind, name, namespace, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rollout status: %w", err)
+		}
+
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func RolloutHistory(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := request.RequireString("kind")
+		if err != nil {
+			return nil, fmt.Errorf("required kind")
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("required namespace")
+		}
+		revision := int64(request.GetInt("revision", 0))
+
+		result, err := client.RolloutHistory(ctx, kind, name, namespace, revision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rollout history: %w", err)
+		}
+
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func RolloutUndo(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := request.RequireString("kind")
+		if err != nil {
+			return nil, fmt.Errorf("required kind")
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("required namespace")
+		}
+		toRevision := int64(request.GetInt("toRevision", 0))
+
+		result, err := client.RolloutUndo(ctx, kind, name, namespace, toRevision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to undo rollout: %w", err)
+		}
+
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// exec 最多保留的stdout和stderr字节数，以及允许的最长超时时间
+const (
+	execMaxOutputBytes = 64 * 1024
+	execMaxTimeout     = 5 * time.Minute
+)
+
+// ExecInPod 只允许执行allowedCommands中的命令，见k8s.IsCommandAllowed
+func ExecInPod(registry *k8s.Registry, allowedCommands []string) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		namespace, err := request.RequireString("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("required namespace")
+		}
+		command, err := request.RequireStringSlice("command")
+		if err != nil {
+			return nil, fmt.Errorf("required command")
+		}
+		if !k8s.IsCommandAllowed(command, allowedCommands) {
+			return nil, fmt.Errorf("command %q is not allowed, allowed commands: %q", strings.Join(command, " "), allowedCommands)
+		}
+		timeout := time.Duration(request.GetInt("timeoutSeconds", 30)) * time.Second
+		if timeout <= 0 || timeout > execMaxTimeout {
+			timeout = execMaxTimeout
+		}
+
+		result, err := client.ExecInPod(ctx, namespace, name, command, k8s.ExecOptions{
+			Container:      request.GetString("containerName", ""),
+			Timeout:        timeout,
+			MaxOutputBytes: execMaxOutputBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// progressReporter 在请求带有progressToken时返回发送 notifications/progress 的函数，否则返回nil
+func progressReporter(ctx context.Context, request mcp.CallToolRequest) k8s.DrainProgress {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return nil
+	}
+	token := request.Params.Meta.ProgressToken
+	return func(done, total int, message string) {
+		_ = mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      done,
+			"total":         total,
+			"message":       message,
+		})
+	}
+}
+
+func CordonNode(registry *k8s.Registry, unschedulable bool) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		result, err := client.CordonNode(ctx, name, unschedulable)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+// drainMaxTimeout 是drainNode等待驱逐的最长时间
+const drainMaxTimeout = time.Hour
+
+func DrainNode(registry *k8s.Registry) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		name, err := request.RequireString("name")
+		if err != nil {
+			return nil, fmt.Errorf("required name")
+		}
+		// 超时为0时节点会先被cordon，然后所有的驱逐立即失败，所以在修改节点之前拒绝
+		timeout := time.Duration(request.GetInt("timeoutSeconds", 300)) * time.Second
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeoutSeconds must be positive")
+		}
+		if timeout > drainMaxTimeout {
+			timeout = drainMaxTimeout
+		}
+		opts := k8s.DrainOptions{
+			DeleteEmptyDirData: request.GetBool("deleteEmptyDirData", false),
+			Force:              request.GetBool("force", false),
+			GracePeriodSeconds: int64(request.GetInt("gracePeriodSeconds", -1)),
+			Timeout:            timeout,
+			DryRun:             request.GetBool("dryRun", false),
+		}
+		result, err := client.DrainNode(ctx, name, opts, progressReporter(ctx, request))
+		if err != nil {
+			return nil, fmt.Errorf("failed to drain node: %w", err)
+		}
+		jsonResponse, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response: %w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/boqier/kube-mcp-server/pkg/policy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PolicyEnforcer 在调用处理函数之前用策略检查每次工具调用和资源读取
+// 需要注册在CallerIdentities的中间件之后，这样才能看到会话的身份
+type PolicyEnforcer struct {
+	policy   *policy.Policy
+	registry *k8s.Registry
+}
+
+func NewPolicyEnforcer(p *policy.Policy, registry *k8s.Registry) *PolicyEnforcer {
+	return &PolicyEnforcer{policy: p, registry: registry}
+}
+
+// ToolMiddleware 一次调用涉及多个对象时（applyManifests），任何一个被拒绝整个调用都会被拒绝
+func (e *PolicyEnforcer) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		requests, err := e.toolRequests(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.check(requests); err != nil {
+			return nil, err
+		}
+		return next(ctx, request)
+	}
+}
+
+// ResourceMiddleware 检查 k8s:// 资源的读取，读取单个对象的动作是get，列表是list
+func (e *PolicyEnforcer) ResourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		base := e.baseRequest(ctx, "resources/read", "")
+		if strings.HasPrefix(request.Params.URI, "k8s://") {
+			uri, err := parseResourceURI(request.Params.URI)
+			if err != nil {
+				return nil, err
+			}
+			base.Verb = "get"
+			if uri.name == "" {
+				base.Verb = "list"
+			}
+			target := resolveTarget(e.registry, uri.cluster, uri.kind, uri.name, uri.namespace)
+			if err := e.check([]policy.Request{withTarget(base, target)}); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx, request)
+	}
+}
+
+func (e *PolicyEnforcer) check(requests []policy.Request) error {
+	for _, request := range requests {
+		if decision := e.policy.Evaluate(request); !decision.Allowed {
+			return &policy.DeniedError{Decision: decision, Request: request}
+		}
+	}
+	return nil
+}
+
+// baseRequest 返回带有调用方身份的请求
+func (e *PolicyEnforcer) baseRequest(ctx context.Context, tool, verb string) policy.Request {
+	request := policy.Request{Tool: tool, Verb: verb}
+	request.User, request.Groups = callerOf(ctx, e.registry)
+	return request
+}
+
+// withTarget 返回针对一个对象的请求
+func withTarget(request policy.Request, target callTarget) policy.Request {
+	request.Cluster, request.Kind, request.Namespace, request.AllNamespaces = target.Cluster, target.Kind, target.Namespace, target.AllNamespaces
+	return request
+}
+
+// toolRequests 从工具的参数中取出要检查的操作
+func (e *PolicyEnforcer) toolRequests(ctx context.Context, request mcp.CallToolRequest) ([]policy.Request, error) {
+	tool := request.Params.Name
+	base := e.baseRequest(ctx, tool, toolVerbs[tool])
+	targets, err := toolTargets(ctx, e.registry, request)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]policy.Request, 0, len(targets))
+	for _, target := range targets {
+		requests = append(requests, withTarget(base, target))
+	}
+	return requests, nil
+}
@@ -2,12 +2,11 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// 让模型直到用Kind的时候要首字符大写
+// 告诉模型kind参数支持的写法
 func UseKindPrompt() func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 		return mcp.NewGetPromptResult(
@@ -15,7 +14,7 @@ func UseKindPrompt() func(ctx context.Context, request mcp.GetPromptRequest) (*m
 			[]mcp.PromptMessage{
 				mcp.NewPromptMessage(
 					mcp.RoleAssistant,
-					mcp.NewTextContent(fmt.Sprintf("When using the tools, make sure you pass parameters like kind=Pod, Deployment, Service, Ingress, etc.")),
+					mcp.NewTextContent("When using the tools, kind accepts the Kind (Pod, Deployment), the plural or singular resource name (pods, deployment), a short name (po, deploy) case-insensitively, or a group-qualified name (deployments.apps, Deployment.v1.apps) when the same kind exists in several API groups."),
 				),
 			},
 		), nil
@@ -2,11 +2,22 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
+// 资源URI中表示集群级别对象或所有命名空间的占位符
+const anyNamespace = "_"
+
 func GetManager(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 	content, err := os.ReadFile("./kube-mcp-server/docs/manager.txt")
 	if err != nil {
@@ -19,3 +30,238 @@ func GetManager(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.Res
 		},
 	}, nil
 }
+
+// resourceURI 是解析后的 k8s:// 资源地址，name为空时表示对象列表
+type resourceURI struct {
+	cluster   string
+	namespace string
+	kind      string
+	name      string
+}
+
+// parseResourceURI 解析 k8s://{cluster}/{namespace}/{kind}/{name} 和 k8s://{namespace}/{kind}
+func parseResourceURI(uri string) (resourceURI, error) {
+	path, ok := strings.CutPrefix(uri, "k8s://")
+	if !ok {
+		return resourceURI{}, fmt.Errorf("unsupported resource uri %s", uri)
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil || unescaped == "" {
+			return resourceURI{}, fmt.Errorf("invalid resource uri %s", uri)
+		}
+		parts[i] = unescaped
+	}
+	var parsed resourceURI
+	switch len(parts) {
+	case 2:
+		parsed = resourceURI{namespace: parts[0], kind: parts[1]}
+	case 4:
+		parsed = resourceURI{cluster: parts[0], namespace: parts[1], kind: parts[2], name: parts[3]}
+	default:
+		return resourceURI{}, fmt.Errorf("invalid resource uri %s, use k8s://{cluster}/{namespace}/{kind}/{name} or k8s://{namespace}/{kind}", uri)
+	}
+	if parsed.namespace == anyNamespace {
+		parsed.namespace = ""
+	}
+	return parsed, nil
+}
+
+// checkURIScope 单个对象的地址中 _ 只能用于集群级别的资源，命名空间级别的对象必须给出命名空间
+func checkURIScope(client *k8s.Client, uri resourceURI) error {
+	if uri.name == "" || uri.namespace != "" {
+		return nil
+	}
+	kind, namespaced, err := client.ResolveKindScope(uri.kind)
+	if err != nil {
+		return err
+	}
+	if namespaced {
+		return fmt.Errorf("%s is namespaced, use k8s://{cluster}/{namespace}/%s/%s instead of %s", kind, uri.kind, uri.name, anyNamespace)
+	}
+	return nil
+}
+
+// objectURI 返回对象对应的资源地址
+func objectURI(cluster, namespace, kind, name string) string {
+	if namespace == "" {
+		namespace = anyNamespace
+	}
+	return fmt.Sprintf("k8s://%s/%s/%s/%s", url.PathEscape(cluster), url.PathEscape(namespace), url.PathEscape(kind), url.PathEscape(name))
+}
+
+func ReadKubernetesResource(registry *k8s.Registry) func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		uri, err := parseResourceURI(request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		client, err := registry.ClientFor(ctx, uri.cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get client for cluster %q: %w", uri.cluster, err)
+		}
+		if err := checkURIScope(client, uri); err != nil {
+			return nil, err
+		}
+		cluster := uri.cluster
+		if cluster == "" {
+			cluster = registry.DefaultCluster()
+		}
+
+		var content interface{}
+		if uri.name != "" {
+			obj, err := client.GetResource(ctx, uri.kind, uri.name, uri.namespace)
+			if err != nil {
+				return nil, err
+			}
+			content, err = k8s.Projection{StripManaged: true}.Apply(obj)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			list, err := client.ListResources(ctx, uri.kind, uri.namespace, "", "", k8s.ListPage{}, k8s.Projection{})
+			if err != nil {
+				return nil, err
+			}
+			items := list.Items
+			for _, item := range items {
+				namespace, _ := item["namespace"].(string)
+				name, _ := item["name"].(string)
+				item["uri"] = objectURI(cluster, namespace, uri.kind, name)
+			}
+			content = items
+		}
+		jsonResponse, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(jsonResponse),
+			},
+		}, nil
+	}
+}
+
+// ResourceSubscriptions 保存每个会话订阅的资源地址，对象变化时向会话发送 notifications/resources/updated
+type ResourceSubscriptions struct {
+	lock sync.Mutex
+	// sessionID -> uri -> 取消订阅的函数
+	sessions map[string]map[string]func()
+}
+
+func NewResourceSubscriptions() *ResourceSubscriptions {
+	return &ResourceSubscriptions{sessions: make(map[string]map[string]func())}
+}
+
+// RemoveSession 取消会话的全部资源订阅
+func (r *ResourceSubscriptions) RemoveSession(sessionID string) {
+	r.lock.Lock()
+	subs := r.sessions[sessionID]
+	delete(r.sessions, sessionID)
+	r.lock.Unlock()
+	for _, stop := range subs {
+		stop()
+	}
+}
+
+func (r *ResourceSubscriptions) remove(sessionID, uri string) bool {
+	r.lock.Lock()
+	stop, ok := r.sessions[sessionID][uri]
+	if ok {
+		delete(r.sessions[sessionID], uri)
+	}
+	r.lock.Unlock()
+	if ok {
+		stop()
+	}
+	return ok
+}
+
+func SubscribeResource(registry *k8s.Registry, subscriptions *ResourceSubscriptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := sessionIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mcpServer := server.ServerFromContext(ctx)
+		if mcpServer == nil {
+			return nil, fmt.Errorf("mcp server is not available in context")
+		}
+		rawURI, err := request.RequireString("uri")
+		if err != nil {
+			return nil, err
+		}
+		uri, err := parseResourceURI(rawURI)
+		if err != nil {
+			return nil, err
+		}
+		client, err := registry.ClientFor(ctx, uri.cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get client for cluster %q: %w", uri.cluster, err)
+		}
+		if err := checkURIScope(client, uri); err != nil {
+			return nil, err
+		}
+
+		subscriptions.lock.Lock()
+		_, exists := subscriptions.sessions[sessionID][rawURI]
+		subscriptions.lock.Unlock()
+		if exists {
+			return mcp.NewToolResultText(fmt.Sprintf("already subscribed to %s", rawURI)), nil
+		}
+
+		onChange := func(change k8s.ResourceChange) {
+			if uri.name != "" && change.Name != uri.name {
+				return
+			}
+			if uri.namespace != "" && change.Namespace != uri.namespace {
+				return
+			}
+			err := mcpServer.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{
+				"uri": rawURI,
+			})
+			if errors.Is(err, server.ErrSessionNotFound) {
+				go subscriptions.remove(sessionID, rawURI)
+			}
+		}
+		stop, err := client.WatchResources(ctx, k8s.WatchOptions{Kind: uri.kind, Namespace: uri.namespace}, onChange)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions.lock.Lock()
+		if subscriptions.sessions[sessionID] == nil {
+			subscriptions.sessions[sessionID] = make(map[string]func())
+		}
+		// 并发的重复订阅只保留一个
+		if _, exists := subscriptions.sessions[sessionID][rawURI]; exists {
+			subscriptions.lock.Unlock()
+			stop()
+			return mcp.NewToolResultText(fmt.Sprintf("already subscribed to %s", rawURI)), nil
+		}
+		subscriptions.sessions[sessionID][rawURI] = stop
+		subscriptions.lock.Unlock()
+		return mcp.NewToolResultText(fmt.Sprintf("subscribed to %s, notifications/resources/updated is sent when it changes", rawURI)), nil
+	}
+}
+
+func UnsubscribeResource(subscriptions *ResourceSubscriptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := sessionIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		uri, err := request.RequireString("uri")
+		if err != nil {
+			return nil, err
+		}
+		if !subscriptions.remove(sessionID, uri) {
+			return nil, fmt.Errorf("not subscribed to %s", uri)
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("unsubscribed from %s", uri)), nil
+	}
+}
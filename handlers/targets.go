@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boqier/kube-mcp-server/pkg/auth"
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolVerbs 是每个工具在策略和审计中的动作，没有列出的工具动作为空，只能按工具名匹配
+var toolVerbs = map[string]string{
+	"listClusters":         "list",
+	"whoAmI":               "get",
+	"getAPIResources":      "list",
+	"getResource":          "get",
+	"describeResource":     "get",
+	"listResources":        "list",
+	"getPodsLogs":          "logs",
+	"getWorkloadLogs":      "logs",
+	"diagnosePod":          "get",
+	"getPodMetrics":        "get",
+	"getNodeMetrics":       "get",
+	"topPods":              "list",
+	"topNodes":             "list",
+	"rightsizingReport":    "list",
+	"canI":                 "review",
+	"whoCan":               "review",
+	"getEvents":            "list",
+	"getIngresses":         "list",
+	"rolloutStatus":        "get",
+	"rolloutHistory":       "get",
+	"watchResources":       "watch",
+	"unwatchResources":     "unwatch",
+	"listWatches":          "list",
+	"subscribeResource":    "watch",
+	"unsubscribeResource":  "unwatch",
+	"queryAuditLog":        "list",
+	"execInPod":            "exec",
+	"rolloutRestart":       "restart",
+	"scaleResource":        "scale",
+	"rolloutUndo":          "rollback",
+	"cordonNode":           "cordon",
+	"uncordonNode":         "uncordon",
+	"drainNode":            "drain",
+	"deleteResource":       "delete",
+	"createResourceJSON":   "apply",
+	"createResourceYAML":   "apply",
+	"applyManifests":       "apply",
+	"get_metric_names":     "query",
+	"query_instant":        "query",
+	"query_range":          "query",
+	"get_alerts":           "query",
+	"query_logs_instant":   "query",
+	"query_logs_range":     "query",
+	"get_log_labels":       "query",
+	"get_log_label_values": "query",
+	"get_log_streams":      "query",
+	"send_to_feishu":       "send",
+}
+
+// readVerbs 是不修改任何东西的动作
+var readVerbs = map[string]bool{
+	"get":     true,
+	"list":    true,
+	"logs":    true,
+	"watch":   true,
+	"unwatch": true,
+	"review":  true,
+	"query":   true,
+}
+
+// toolKinds 是参数中没有kind、但总是操作同一种资源的工具
+var toolKinds = map[string]string{
+	"getPodsLogs":     "Pod",
+	"getWorkloadLogs": "Pod",
+	"diagnosePod":     "Pod",
+	"getPodMetrics":   "Pod",
+	"execInPod":       "Pod",
+	"topPods":         "Pod",
+	"getNodeMetrics":  "Node",
+	"topNodes":        "Node",
+	"cordonNode":      "Node",
+	"uncordonNode":    "Node",
+	"drainNode":       "Node",
+	"getEvents":       "Event",
+	"getIngresses":    "Ingress",
+}
+
+// namespaceDefaults 是命名空间参数默认为default的工具，和它们的处理函数保持一致
+var namespaceDefaults = map[string]bool{
+	"getPodsLogs":     true,
+	"getWorkloadLogs": true,
+	"diagnosePod":     true,
+}
+
+// toolNameArguments 是各个工具中对象名称的参数，按顺序取第一个不为空的
+var toolNameArguments = []string{"name", "Name", "podName", "nodeName"}
+
+// callTarget 是一次工具调用要操作的对象，Kind为空表示工具不针对具体的资源，Name为空表示一组对象
+// AllNamespaces 表示Kind是命名空间级别的资源但没有指定命名空间
+type callTarget struct {
+	Cluster       string `json:"cluster,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	AllNamespaces bool   `json:"allNamespaces,omitempty"`
+}
+
+// callerOf 返回调用方的身份：优先使用认证得到的身份，其次是模拟的身份
+func callerOf(ctx context.Context, registry *k8s.Registry) (string, []string) {
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		return identity.User, identity.Groups
+	}
+	if identity, ok := k8s.IdentityFromContext(ctx); ok {
+		return identity.User, identity.Groups
+	}
+	identity := registry.DefaultIdentity()
+	return identity.User, identity.Groups
+}
+
+// resolveTarget 填入集群、规范的Kind和命名空间，集群级别的资源命名空间为空
+// kind无法解析时保持原样，处理函数会返回具体的错误
+func resolveTarget(registry *k8s.Registry, cluster, kind, name, namespace string) callTarget {
+	if cluster == "" {
+		cluster = registry.DefaultCluster()
+	}
+	target := callTarget{Cluster: cluster, Kind: kind, Name: name, Namespace: namespace}
+	if kind == "" {
+		return target
+	}
+	client, err := registry.Client(cluster)
+	if err != nil {
+		return target
+	}
+	if key, namespaced, err := client.ResolveKindScope(kind); err == nil {
+		target.Kind = key
+		if !namespaced {
+			target.Namespace = ""
+		} else if target.Namespace == "" {
+			target.AllNamespaces = true
+		}
+	}
+	return target
+}
+
+// toolTargets 从工具的参数中取出这次调用要操作的对象，apply类的工具还包括会被自动创建的命名空间
+func toolTargets(ctx context.Context, registry *k8s.Registry, request mcp.CallToolRequest) ([]callTarget, error) {
+	tool := request.Params.Name
+	cluster := request.GetString("cluster", "")
+	namespace := request.GetString("namespace", "")
+	if namespace == "" && namespaceDefaults[tool] {
+		namespace = "default"
+	}
+	var name string
+	for _, argument := range toolNameArguments {
+		if name = request.GetString(argument, ""); name != "" {
+			break
+		}
+	}
+
+	switch tool {
+	case "createResourceJSON", "createResourceYAML", "applyManifests":
+		argument := map[string]string{
+			"createResourceJSON": "jsonManifest",
+			"createResourceYAML": "yamlManifest",
+			"applyManifests":     "manifests",
+		}[tool]
+		manifests := request.GetString(argument, "")
+		if manifests == "" {
+			// 参数缺失由处理函数报错
+			return []callTarget{resolveTarget(registry, cluster, request.GetString("kind", ""), "", namespace)}, nil
+		}
+		if cluster == "" {
+			cluster = registry.DefaultCluster()
+		}
+		client, err := registry.Client(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get client for cluster %q: %w", cluster, err)
+		}
+		refs, err := client.ManifestRefs(ctx, manifests, namespace, tool != "applyManifests")
+		if err != nil {
+			return nil, err
+		}
+		targets := make([]callTarget, 0, len(refs))
+		for _, ref := range refs {
+			targets = append(targets, callTarget{Cluster: cluster, Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace})
+		}
+		return targets, nil
+	case "subscribeResource":
+		uri, err := parseResourceURI(request.GetString("uri", ""))
+		if err != nil {
+			return nil, err
+		}
+		return []callTarget{resolveTarget(registry, uri.cluster, uri.kind, uri.name, uri.namespace)}, nil
+	case "canI", "whoCan":
+		// kind、name和verb参数是被检查的权限，不是这次调用要操作的资源
+		return []callTarget{resolveTarget(registry, cluster, "", "", namespace)}, nil
+	}
+
+	kind := toolKinds[tool]
+	if kind == "" {
+		kind = request.GetString("kind", "")
+	}
+	return []callTarget{resolveTarget(registry, cluster, kind, name, namespace)}, nil
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/boqier/kube-mcp-server/pkg/k8s"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 推送通知时使用的logger名称，客户端可以据此区分集群变化和其他日志
+const watchLogger = "kubernetes"
+
+// 每个会话最多同时存在的订阅数
+const maxWatchesPerSession = 20
+
+// subscription 是一个会话对某类资源的订阅
+type subscription struct {
+	ID        string `json:"id"`
+	SessionID string `json:"-"`
+	Cluster   string `json:"cluster"`
+	k8s.WatchOptions
+	stop func()
+}
+
+// WatchManager 保存所有会话的订阅，对象变化通过 notifications/message 推送给订阅它的会话
+// 会话断开时需要调用RemoveSession释放informer上注册的处理函数
+type WatchManager struct {
+	lock          sync.Mutex
+	nextID        int
+	subscriptions map[string]*subscription
+}
+
+func NewWatchManager() *WatchManager {
+	return &WatchManager{subscriptions: make(map[string]*subscription)}
+}
+
+func (m *WatchManager) add(sub *subscription) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	count := 0
+	for _, existing := range m.subscriptions {
+		if existing.SessionID == sub.SessionID {
+			count++
+		}
+	}
+	if count >= maxWatchesPerSession {
+		return fmt.Errorf("too many watches in this session (max %d), unwatch some of them first", maxWatchesPerSession)
+	}
+	m.nextID++
+	sub.ID = fmt.Sprintf("watch-%d", m.nextID)
+	m.subscriptions[sub.ID] = sub
+	return nil
+}
+
+// remove 取消订阅，sessionID不匹配时不允许取消其他会话的订阅
+func (m *WatchManager) remove(id, sessionID string) bool {
+	m.lock.Lock()
+	sub, ok := m.subscriptions[id]
+	if !ok || sub.SessionID != sessionID {
+		m.lock.Unlock()
+		return false
+	}
+	delete(m.subscriptions, id)
+	m.lock.Unlock()
+	if sub.stop != nil {
+		sub.stop()
+	}
+	return true
+}
+
+// RemoveSession 取消会话的全部订阅
+func (m *WatchManager) RemoveSession(sessionID string) {
+	m.lock.Lock()
+	var ids []string
+	for id, sub := range m.subscriptions {
+		if sub.SessionID == sessionID {
+			ids = append(ids, id)
+		}
+	}
+	m.lock.Unlock()
+	for _, id := range ids {
+		m.remove(id, sessionID)
+	}
+}
+
+func (m *WatchManager) list(sessionID string) []*subscription {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	subs := []*subscription{}
+	for _, sub := range m.subscriptions {
+		if sub.SessionID == sessionID {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+func sessionIDFromContext(ctx context.Context) (string, error) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil || session.SessionID() == "" {
+		return "", fmt.Errorf("watching requires a client session, it is not available in stateless mode")
+	}
+	return session.SessionID(), nil
+}
+
+func WatchResources(registry *k8s.Registry, manager *WatchManager) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := sessionIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mcpServer := server.ServerFromContext(ctx)
+		if mcpServer == nil {
+			return nil, fmt.Errorf("mcp server is not available in context")
+		}
+		client, err := clientFromRequest(ctx, registry, request)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := request.RequireString("kind")
+		if err != nil {
+			return nil, err
+		}
+		cluster := request.GetString("cluster", "")
+		if cluster == "" {
+			cluster = registry.DefaultCluster()
+		}
+		sub := &subscription{
+			SessionID: sessionID,
+			Cluster:   cluster,
+			WatchOptions: k8s.WatchOptions{
+				Kind:          kind,
+				Namespace:     request.GetString("namespace", ""),
+				LabelSelector: request.GetString("labelSelector", ""),
+				WarningEvents: request.GetBool("warningEvents", true),
+			},
+		}
+		if err := manager.add(sub); err != nil {
+			return nil, err
+		}
+
+		onChange := func(change k8s.ResourceChange) {
+			level := mcp.LoggingLevelInfo
+			if change.Type == k8s.ChangeWarning {
+				level = mcp.LoggingLevelWarning
+			}
+			// 客户端显式订阅了变化，所以不受会话日志级别的限制
+			err := mcpServer.SendNotificationToSpecificClient(sessionID, "notifications/message", map[string]any{
+				"level":  level,
+				"logger": watchLogger,
+				"data": map[string]interface{}{
+					"watchId": sub.ID,
+					"cluster": sub.Cluster,
+					"change":  change,
+				},
+			})
+			// 会话已经不存在，在informer的回调之外取消订阅
+			if errors.Is(err, server.ErrSessionNotFound) {
+				go manager.remove(sub.ID, sessionID)
+			}
+		}
+		stop, err := client.WatchResources(ctx, sub.WatchOptions, onChange)
+		if err != nil {
+			manager.remove(sub.ID, sessionID)
+			return nil, err
+		}
+		manager.lock.Lock()
+		if _, ok := manager.subscriptions[sub.ID]; ok {
+			sub.stop = stop
+			stop = nil
+		}
+		manager.lock.Unlock()
+		// 注册期间会话已经断开
+		if stop != nil {
+			stop()
+			return nil, fmt.Errorf("session closed while creating the watch")
+		}
+
+		jsonResponse, err := json.Marshal(map[string]interface{}{
+			"watchId": sub.ID,
+			"cluster": sub.Cluster,
+			"message": fmt.Sprintf("changes of %s are pushed as notifications/message with logger %q", kind, watchLogger),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
+
+func UnwatchResources(manager *WatchManager) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := sessionIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		watchID := request.GetString("watchId", "")
+		if watchID == "" {
+			manager.RemoveSession(sessionID)
+			return mcp.NewToolResultText("all watches of this session removed"), nil
+		}
+		if !manager.remove(watchID, sessionID) {
+			return nil, fmt.Errorf("watch %s not found in this session", watchID)
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("watch %s removed", watchID)), nil
+	}
+}
+
+func ListWatches(manager *WatchManager) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := sessionIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jsonResponse, err := json.Marshal(manager.list(sessionID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize response:%w", err)
+		}
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	}
+}
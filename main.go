@@ -2,15 +2,24 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/boqier/kube-mcp-server/handlers"
+	"github.com/boqier/kube-mcp-server/pkg/audit"
+	"github.com/boqier/kube-mcp-server/pkg/auth"
 	"github.com/boqier/kube-mcp-server/pkg/k8s"
 	"github.com/boqier/kube-mcp-server/pkg/loki"
+	"github.com/boqier/kube-mcp-server/pkg/policy"
 	"github.com/boqier/kube-mcp-server/pkg/prometheus"
 	"github.com/boqier/kube-mcp-server/prompts"
 	"github.com/boqier/kube-mcp-server/resources"
@@ -24,8 +33,120 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-func addResources(s *server.MCPServer) {
+
+// getEnvIntOrDefault 和getEnvOrDefault相同，环境变量不是整数时退出
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %s must be an integer: %v", key, err))
+	}
+	return n
+}
+
+// splitList 把逗号分隔的配置转换为列表，忽略空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// httpServerOptions 是sse和streamable-http模式的认证和TLS配置
+type httpServerOptions struct {
+	authenticators auth.Chain
+	tlsConfig      *tls.Config
+	tlsCertFile    string
+	tlsKeyFile     string
+}
+
+// buildHTTPServerOptions 按配置创建认证方式，没有配置任何方式时不认证
+// 认证方式按 客户端证书、静态token、JWT 的顺序尝试，第一个认出凭据的决定结果
+func buildHTTPServerOptions(tokenFile string, jwtOptions auth.JWTOptions, certFile, keyFile, clientCAFile string) (httpServerOptions, error) {
+	options := httpServerOptions{tlsCertFile: certFile, tlsKeyFile: keyFile}
+	if (certFile == "") != (keyFile == "") {
+		return options, fmt.Errorf("-tls-cert-file and -tls-key-file must be set together")
+	}
+	if clientCAFile != "" {
+		if certFile == "" {
+			return options, fmt.Errorf("-tls-client-ca-file requires -tls-cert-file and -tls-key-file")
+		}
+		options.authenticators = append(options.authenticators, auth.ClientCert{})
+	}
+	if tokenFile != "" {
+		tokens, err := auth.LoadStaticTokens(tokenFile)
+		if err != nil {
+			return options, err
+		}
+		options.authenticators = append(options.authenticators, tokens)
+	}
+	if jwtOptions.JWKSFile != "" {
+		if jwtOptions.Audience == "" {
+			return options, fmt.Errorf("-auth-jwt-audience is required when -auth-jwks-file is set")
+		}
+		if jwtOptions.Issuer == "" {
+			fmt.Println("Warning: -auth-jwt-issuer is not set, tokens from any issuer trusted by the JWKS are accepted")
+		}
+		jwtAuthenticator, err := auth.NewJWTAuthenticator(jwtOptions)
+		if err != nil {
+			return options, err
+		}
+		options.authenticators = append(options.authenticators, jwtAuthenticator)
+	}
+	if certFile != "" {
+		tlsConfig, err := auth.ServerTLSConfig(clientCAFile, len(options.authenticators) == 1 && clientCAFile != "")
+		if err != nil {
+			return options, err
+		}
+		options.tlsConfig = tlsConfig
+	}
+	if len(options.authenticators) == 0 {
+		fmt.Println("Warning: no authentication configured, anyone who can reach the port can call every tool")
+	}
+	return options, nil
+}
+
+// serveHTTP 启动HTTP服务直到ctx被取消，配置了证书时使用HTTPS，配置了认证方式时每个请求都要先通过认证
+func serveHTTP(ctx context.Context, port string, handler http.Handler, options httpServerOptions) error {
+	if len(options.authenticators) > 0 {
+		handler = options.authenticators.Wrap(handler)
+	}
+	srv := &http.Server{
+		Addr:      ":" + port,
+		Handler:   handler,
+		TLSConfig: options.tlsConfig,
+	}
+	go func() {
+		<-ctx.Done()
+		// SSE和GET流是长连接，等待一段时间后直接关闭
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+		}
+	}()
+	var err error
+	if options.tlsCertFile != "" {
+		err = srv.ListenAndServeTLS(options.tlsCertFile, options.tlsKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func addResources(s *server.MCPServer, registry *k8s.Registry) {
 	s.AddResource(resources.ManagerResource(), handlers.GetManager)
+	s.AddResourceTemplate(resources.ObjectResourceTemplate(), handlers.ReadKubernetesResource(registry))
+	s.AddResourceTemplate(resources.KindResourceTemplate(), handlers.ReadKubernetesResource(registry))
 }
 func main() {
 	// 创建上下文，用于管理Informer生命周期
@@ -41,15 +162,6 @@ func main() {
 		cancel()
 	}()
 
-	s := server.NewMCPServer(
-		"MCP K8S SERVER",
-		"0.3.0",
-		server.WithResourceCapabilities(true, true),
-	)
-	client, err := k8s.NewClient("")
-	if err != nil {
-		panic(err)
-	}
 	var promClient *prometheus.Client
 	var lokiClient *loki.Client
 	var promErr error
@@ -61,6 +173,25 @@ func main() {
 	var enableLoki bool
 	var prometheusURL string
 	var lokiURL string
+	var kubeconfig string
+	var defaultCluster string
+	var execAllowedCommands string
+	var allowExecInSafeMode bool
+	var impersonateUser string
+	var impersonateGroups string
+	var impersonateUserHeader string
+	var impersonateGroupHeader string
+	var impersonateAuthenticated bool
+	var authTokenFile string
+	var jwtOptions auth.JWTOptions
+	var tlsCertFile string
+	var tlsKeyFile string
+	var tlsClientCAFile string
+	var policyFile string
+	var auditOptions audit.Options
+	var auditReadTools bool
+	var confirmChanges bool
+	var confirmTokenTTL time.Duration
 
 	flag.StringVar(&port, "port", getEnvOrDefault("SERVER_PORT", "8080"), "Server port")
 	flag.StringVar(&mode, "mode", getEnvOrDefault("SERVER_MODE", "stdio"), "Server mode: 'stdio', 'sse', or 'streamable-http'")
@@ -69,8 +200,105 @@ func main() {
 	flag.BoolVar(&enableLoki, "enable-loki", false, "Enable Loki integration (default: false)")
 	flag.StringVar(&prometheusURL, "prometheus-url", getEnvOrDefault("PROMETHEUS_URL", "http://127.0.0.1:9090"), "Prometheus server URL")
 	flag.StringVar(&lokiURL, "loki-url", getEnvOrDefault("LOKI_URL", "http://127.0.0.1:3100"), "Loki server URL")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, every context in it is exposed as a cluster")
+	flag.StringVar(&defaultCluster, "default-cluster", getEnvOrDefault("DEFAULT_CLUSTER", ""), "Cluster (kubeconfig context) used when a tool call does not specify one (default: current-context)")
+	flag.StringVar(&execAllowedCommands, "exec-allowed-commands", getEnvOrDefault("EXEC_ALLOWED_COMMANDS", "cat /etc/resolv.conf,cat /etc/hosts,ls *,ps *,df *,nslookup *"), "Comma separated commands that execInPod may run, arguments must match exactly unless the command ends with *")
+	flag.BoolVar(&allowExecInSafeMode, "allow-exec-in-safe-mode", false, "Keep the execInPod tool enabled in safe mode")
+	flag.StringVar(&impersonateUser, "impersonate-user", getEnvOrDefault("IMPERSONATE_USER", ""), "Default user that tool calls impersonate when the caller has no identity (default: the server's own identity)")
+	flag.StringVar(&impersonateGroups, "impersonate-groups", getEnvOrDefault("IMPERSONATE_GROUPS", ""), "Comma separated groups impersonated together with -impersonate-user")
+	flag.StringVar(&impersonateUserHeader, "impersonate-user-header", getEnvOrDefault("IMPERSONATE_USER_HEADER", ""), "HTTP header carrying the caller's user in sse and streamable-http mode, only enable behind an authenticating proxy (default: disabled)")
+	flag.StringVar(&impersonateGroupHeader, "impersonate-group-header", getEnvOrDefault("IMPERSONATE_GROUP_HEADER", ""), "HTTP header carrying the caller's groups, used together with -impersonate-user-header")
+	flag.BoolVar(&impersonateAuthenticated, "impersonate-authenticated", false, "Impersonate the authenticated caller of sse and streamable-http requests")
+	flag.StringVar(&authTokenFile, "auth-token-file", getEnvOrDefault("AUTH_TOKEN_FILE", ""), "Static bearer token file (token,user,uid,\"group1,group2\" per line) for sse and streamable-http mode")
+	flag.StringVar(&jwtOptions.JWKSFile, "auth-jwks-file", getEnvOrDefault("AUTH_JWKS_FILE", ""), "Local JWKS file used to verify JWT/OIDC bearer tokens")
+	flag.StringVar(&jwtOptions.Issuer, "auth-jwt-issuer", getEnvOrDefault("AUTH_JWT_ISSUER", ""), "Required iss claim of JWT bearer tokens")
+	flag.StringVar(&jwtOptions.Audience, "auth-jwt-audience", getEnvOrDefault("AUTH_JWT_AUDIENCE", ""), "Required aud claim of JWT bearer tokens, must be set together with -auth-jwks-file")
+	flag.StringVar(&jwtOptions.UsernameClaim, "auth-jwt-username-claim", getEnvOrDefault("AUTH_JWT_USERNAME_CLAIM", "sub"), "JWT claim used as the user name")
+	flag.StringVar(&jwtOptions.GroupsClaim, "auth-jwt-groups-claim", getEnvOrDefault("AUTH_JWT_GROUPS_CLAIM", "groups"), "JWT claim used as the groups")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", getEnvOrDefault("TLS_CERT_FILE", ""), "Serve sse and streamable-http over HTTPS with this certificate")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", getEnvOrDefault("TLS_KEY_FILE", ""), "Private key of -tls-cert-file")
+	flag.StringVar(&tlsClientCAFile, "tls-client-ca-file", getEnvOrDefault("TLS_CLIENT_CA_FILE", ""), "Authenticate clients by certificates signed by this CA (CN is the user, O are the groups)")
+	flag.StringVar(&policyFile, "policy-file", getEnvOrDefault("POLICY_FILE", ""), "Policy file that allows or denies tool calls by tool, verb, kind, namespace, cluster and caller")
+	flag.StringVar(&auditOptions.Path, "audit-log", getEnvOrDefault("AUDIT_LOG", ""), "Append a JSON line for every tool call that changes something to this file")
+	flag.IntVar(&auditOptions.MaxSizeMB, "audit-max-size-mb", getEnvIntOrDefault("AUDIT_MAX_SIZE_MB", 100), "Rotate the audit log when it reaches this size in megabytes, 0 disables rotation")
+	flag.IntVar(&auditOptions.MaxBackups, "audit-max-backups", getEnvIntOrDefault("AUDIT_MAX_BACKUPS", 10), "Number of rotated audit log files to keep, 0 keeps all")
+	flag.StringVar(&auditOptions.WebhookURL, "audit-webhook-url", getEnvOrDefault("AUDIT_WEBHOOK_URL", ""), "Also POST every audit entry as JSON to this URL")
+	flag.BoolVar(&auditReadTools, "audit-read-tools", false, "Also audit tools that only read")
+	flag.BoolVar(&confirmChanges, "confirm-changes", false, "Require confirmation before deleteResource, rolloutRestart and the apply tools change anything: ask the user through elicitation when the client supports it, otherwise return a plan with a confirmation token")
+	flag.DurationVar(&confirmTokenTTL, "confirm-token-ttl", 5*time.Minute, "How long a confirmation token returned by -confirm-changes stays valid")
 	flag.Parse()
 
+	registry, err := k8s.NewRegistry(ctx, kubeconfig, defaultCluster)
+	if err != nil {
+		panic(err)
+	}
+	defaultIdentity := k8s.Identity{User: impersonateUser, Groups: splitList(impersonateGroups)}
+	if !defaultIdentity.IsZero() {
+		if defaultIdentity.User == "" {
+			panic("-impersonate-groups requires -impersonate-user")
+		}
+		fmt.Printf("Tool calls impersonate %s by default\n", defaultIdentity)
+	}
+	registry.SetDefaultIdentity(defaultIdentity)
+
+	// 会话断开时取消它的所有订阅
+	watchManager := handlers.NewWatchManager()
+	resourceSubscriptions := handlers.NewResourceSubscriptions()
+	hooks := &server.Hooks{}
+	callerIdentities := handlers.NewCallerIdentities(impersonateUserHeader, impersonateGroupHeader, impersonateAuthenticated)
+	hooks.AddAfterInitialize(callerIdentities.RememberSession)
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		watchManager.RemoveSession(session.SessionID())
+		resourceSubscriptions.RemoveSession(session.SessionID())
+		callerIdentities.RemoveSession(session.SessionID())
+	})
+
+	serverOptions := []server.ServerOption{
+		server.WithResourceCapabilities(true, true),
+		server.WithLogging(),
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(callerIdentities.ToolMiddleware),
+		server.WithResourceHandlerMiddleware(callerIdentities.ResourceMiddleware),
+	}
+	var auditLogger *audit.Logger
+	if auditOptions.Path != "" || auditOptions.WebhookURL != "" {
+		auditLogger, err = audit.NewLogger(auditOptions)
+		if err != nil {
+			panic(err)
+		}
+		defer auditLogger.Close()
+		fmt.Printf("Audit log enabled (file: %q, webhook: %q)\n", auditOptions.Path, auditOptions.WebhookURL)
+		// 在策略中间件之前注册，被策略拒绝的调用也会被记录
+		auditor := handlers.NewAuditor(auditLogger, registry, auditReadTools)
+		serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(auditor.ToolMiddleware))
+	}
+	if policyFile != "" {
+		p, err := policy.Load(policyFile)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Policy loaded from %s (%d rules, default effect %s)\n", policyFile, len(p.Rules), p.DefaultEffect)
+		// mcp-go中先注册的中间件在外层，策略在身份中间件之后执行才能看到会话的身份
+		enforcer := handlers.NewPolicyEnforcer(p, registry)
+		serverOptions = append(serverOptions,
+			server.WithToolHandlerMiddleware(enforcer.ToolMiddleware),
+			server.WithResourceHandlerMiddleware(enforcer.ResourceMiddleware),
+		)
+	}
+	if confirmChanges {
+		if confirmTokenTTL <= 0 {
+			panic("-confirm-token-ttl must be positive")
+		}
+		fmt.Printf("Changes require confirmation (token ttl %s)\n", confirmTokenTTL)
+		// 在策略中间件之后注册，被拒绝的调用不会生成计划
+		confirmations := handlers.NewConfirmations(registry, confirmTokenTTL)
+		serverOptions = append(serverOptions,
+			server.WithElicitation(),
+			server.WithToolHandlerMiddleware(confirmations.ToolMiddleware),
+		)
+	}
+	s := server.NewMCPServer("MCP K8S SERVER", "0.3.0", serverOptions...)
+
 	if enablePrometheus {
 		promClient, promErr = prometheus.New(prometheusURL)
 		if promErr != nil {
@@ -95,25 +323,44 @@ func main() {
 		fmt.Println("Loki integration disabled")
 	}
 
-	// 启动Informer并等待缓存同步
-	fmt.Println("Starting Informers...")
-	client.StartInformers(ctx)
-	fmt.Println("Waiting for Informer caches to sync...")
-	if !client.WaitForCacheSync(ctx) {
-		fmt.Println("Warning: Informer caches failed to sync")
-	} else {
-		fmt.Println("Informer caches synced successfully")
+	// 默认集群在启动时就创建客户端并同步Informer缓存，其他集群在第一次被使用时才启动
+	fmt.Printf("Configured clusters: %v (default: %s)\n", registry.Clusters(), registry.DefaultCluster())
+	if _, err := registry.Client(""); err != nil {
+		panic(err)
 	}
 
-	s.AddTool(tools.GetAPIResourcesTool(), handlers.GetAPIResources(client))
-	s.AddTool(tools.GetResourcesTool(), handlers.GetResources(client))
-	s.AddTool(tools.ListResourcesTool(), handlers.ListResources(client))
-	s.AddTool(tools.DescribeResourcesTool(), handlers.DescribeResources(client))
-	s.AddTool(tools.GetPodsLogsTools(), handlers.GetPodsLogs(*client))
-	s.AddTool(tools.GetPodMetricsTool(), handlers.GetPodMetrics(client))
-	s.AddTool(tools.GetNodeMetricsTools(), handlers.GetNodeMetrics(client))
-	s.AddTool(tools.GetEventsTools(), handlers.GetEvents(client))
-	s.AddTool(tools.GetIngressesTool(), handlers.GetIngresses(client))
+	s.AddTool(tools.ListClustersTool(), handlers.ListClusters(registry))
+	s.AddTool(tools.WhoAmITool(), handlers.WhoAmI(registry))
+	s.AddTool(tools.GetAPIResourcesTool(), handlers.GetAPIResources(registry))
+	s.AddTool(tools.GetResourcesTool(), handlers.GetResources(registry))
+	s.AddTool(tools.ListResourcesTool(), handlers.ListResources(registry))
+	s.AddTool(tools.DescribeResourcesTool(), handlers.DescribeResources(registry))
+	s.AddTool(tools.GetPodsLogsTools(), handlers.GetPodsLogs(registry))
+	s.AddTool(tools.GetWorkloadLogsTool(), handlers.GetWorkloadLogs(registry))
+	s.AddTool(tools.DiagnosePodTool(), handlers.DiagnosePod(registry))
+	s.AddTool(tools.GetPodMetricsTool(), handlers.GetPodMetrics(registry))
+	s.AddTool(tools.GetNodeMetricsTools(), handlers.GetNodeMetrics(registry))
+	s.AddTool(tools.TopPodsTool(), handlers.TopPods(registry))
+	s.AddTool(tools.TopNodesTool(), handlers.TopNodes(registry))
+	var usageHistory k8s.UsageHistory
+	if promClient != nil && enablePrometheus {
+		usageHistory = promClient
+	}
+	s.AddTool(tools.CanITool(), handlers.CanI(registry))
+	s.AddTool(tools.WhoCanTool(), handlers.WhoCan(registry))
+	s.AddTool(tools.RightsizingReportTool(), handlers.RightsizingReport(registry, usageHistory))
+	s.AddTool(tools.GetEventsTools(), handlers.GetEvents(registry))
+	s.AddTool(tools.GetIngressesTool(), handlers.GetIngresses(registry))
+	s.AddTool(tools.RolloutStatusTool(), handlers.RolloutStatus(registry))
+	s.AddTool(tools.RolloutHistoryTool(), handlers.RolloutHistory(registry))
+	s.AddTool(tools.WatchResourcesTool(), handlers.WatchResources(registry, watchManager))
+	s.AddTool(tools.UnwatchResourcesTool(), handlers.UnwatchResources(watchManager))
+	s.AddTool(tools.ListWatchesTool(), handlers.ListWatches(watchManager))
+	s.AddTool(tools.SubscribeResourceTool(), handlers.SubscribeResource(registry, resourceSubscriptions))
+	s.AddTool(tools.UnsubscribeResourceTool(), handlers.UnsubscribeResource(resourceSubscriptions))
+	if auditLogger != nil && auditLogger.Queryable() {
+		s.AddTool(tools.QueryAuditLogTool(), handlers.QueryAuditLog(auditLogger))
+	}
 
 	if promClient != nil && enablePrometheus {
 		s.AddTool(tools.GetMetricNamesTool(), handlers.GetMetricNames(promClient))
@@ -129,16 +376,33 @@ func main() {
 		s.AddTool(tools.GetLogLabelValuesTool(), handlers.GetLogLabelValues(lokiClient))
 		s.AddTool(tools.GetLogStreamsTool(), handlers.GetLogStreams(lokiClient))
 	}
+	if !safeMod || allowExecInSafeMode {
+		s.AddTool(tools.ExecInPodTool(), handlers.ExecInPod(registry, splitList(execAllowedCommands)))
+	}
 	s.AddPrompt(prompts.UseKindPrompt(), handlers.UseKindPrompt())
 	s.AddTool(tools.SendToFeishuTool(), handlers.SendToFeishuHandler())
 
 	if !safeMod {
-		s.AddTool(tools.RolloutRestartTool(), handlers.RolloutRestart(client))
-		s.AddTool(tools.DeleteResourceTool(), handlers.DeleteResource(client))
-		s.AddTool(tools.CreateOrUpdateResourceJSONTool(), handlers.CreateOrUpdateResourceJSON(client))
-		s.AddTool(tools.CreateOrUpdateResourceYAMLTool(), handlers.CreateOrUpdateResourceYAML(client))
+		s.AddTool(tools.RolloutRestartTool(), handlers.RolloutRestart(registry))
+		s.AddTool(tools.ScaleResourceTool(), handlers.ScaleResource(registry))
+		s.AddTool(tools.RolloutUndoTool(), handlers.RolloutUndo(registry))
+		s.AddTool(tools.CordonNodeTool(), handlers.CordonNode(registry, true))
+		s.AddTool(tools.UncordonNodeTool(), handlers.CordonNode(registry, false))
+		s.AddTool(tools.DrainNodeTool(), handlers.DrainNode(registry))
+		s.AddTool(tools.DeleteResourceTool(), handlers.DeleteResource(registry))
+		s.AddTool(tools.CreateOrUpdateResourceJSONTool(), handlers.CreateOrUpdateResourceJSON(registry))
+		s.AddTool(tools.CreateOrUpdateResourceYAMLTool(), handlers.CreateOrUpdateResourceYAML(registry))
+		s.AddTool(tools.ApplyManifestsTool(), handlers.ApplyManifests(registry))
+	}
+	addResources(s, registry)
+
+	var httpOptions httpServerOptions
+	if mode == "sse" || mode == "streamable-http" {
+		httpOptions, err = buildHTTPServerOptions(authTokenFile, jwtOptions, tlsCertFile, tlsKeyFile, tlsClientCAFile)
+		if err != nil {
+			panic(err)
+		}
 	}
-	addResources(s)
 	fmt.Println("server starting")
 	switch mode {
 	case "stdio":
@@ -149,24 +413,21 @@ func main() {
 		}
 	case "sse":
 		fmt.Printf("Starting server in SSE mode on port %s...\n", port)
-		sse := server.NewSSEServer(s)
-		if err := sse.Start(":" + port); err != nil {
+		sse := server.NewSSEServer(s, server.WithSSEContextFunc(callerIdentities.HTTPContextFunc))
+		if err := serveHTTP(ctx, port, sse, httpOptions); err != nil {
 			fmt.Printf("Failed to start SSE server: %v\n", err)
 			return
 		}
-		fmt.Printf("SSE server started on port %s\n", port)
-		// 等待上下文取消
-		<-ctx.Done()
 	case "streamable-http":
-		fmt.Printf("Starting server in streamable-http mode on port %s...\n", port)
-		streamableHTTP := server.NewStreamableHTTPServer(s, server.WithStateLess(true))
-		if err := streamableHTTP.Start(":" + port); err != nil {
+		fmt.Printf("Starting server in streamable-http mode on port %s (endpoint: /mcp)...\n", port)
+		// 需要有状态的会话，订阅的变化才能推送到客户端的GET流上
+		streamableHTTP := server.NewStreamableHTTPServer(s, server.WithStateful(true), server.WithHTTPContextFunc(callerIdentities.HTTPContextFunc))
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", streamableHTTP)
+		if err := serveHTTP(ctx, port, mux, httpOptions); err != nil {
 			fmt.Printf("Failed to start streamable-http server: %v\n", err)
 			return
 		}
-		fmt.Printf("Streamable-http server started on port %s (endpoint: http://localhost:%s/mcp)\n", port, port)
-		// 等待上下文取消
-		<-ctx.Done()
 	default:
 		fmt.Printf("Unknown server mode: %s. Use 'stdio', 'sse', or 'streamable-http'.\n", mode)
 		return
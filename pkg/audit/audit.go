@@ -0,0 +1,221 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 调用的结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeDenied  = "denied"
+	// OutcomePlanned 表示调用只返回了需要确认的计划，没有修改任何东西
+	OutcomePlanned = "planned"
+)
+
+// 轮转出的文件名后缀，固定宽度，按字符串排序就是按时间排序
+const backupTimeFormat = "20060102T150405.000000000"
+
+// Target 是一次调用操作的对象，Name为空表示一组对象，AllNamespaces表示涉及所有命名空间
+type Target struct {
+	Cluster       string `json:"cluster,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	AllNamespaces bool   `json:"allNamespaces,omitempty"`
+}
+
+// Entry 是审计日志中的一行
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Groups  []string  `json:"groups,omitempty"`
+	// Method 是调用方的认证方式，为空表示没有认证（stdio或未启用认证）
+	Method string `json:"method,omitempty"`
+	Tool   string `json:"tool"`
+	Verb   string `json:"verb,omitempty"`
+	// ReadOnly 表示工具不修改任何东西
+	ReadOnly  bool                   `json:"readOnly,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Targets   []Target               `json:"targets,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	Error     string                 `json:"error,omitempty"`
+	// DurationMs 是处理函数的耗时，单位毫秒
+	DurationMs int64 `json:"durationMs"`
+	// ResourceVersions 是修改之后对象的resourceVersion，和工具结果中的顺序一致
+	ResourceVersions []string `json:"resourceVersions,omitempty"`
+}
+
+// Options 是审计日志的配置，Path和WebhookURL至少要有一个
+type Options struct {
+	// Path 是JSON lines文件，为空时不写文件，也无法查询
+	Path string
+	// MaxSizeMB 是文件轮转的大小，0表示不轮转
+	MaxSizeMB int
+	// MaxBackups 是保留的轮转文件数量，0表示全部保留
+	MaxBackups int
+	// WebhookURL 不为空时每条记录还会POST到这个地址
+	WebhookURL string
+}
+
+// Logger 只追加写入审计日志，可以被多个goroutine同时使用
+type Logger struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+	webhook    *webhookSink
+}
+
+func NewLogger(options Options) (*Logger, error) {
+	if options.Path == "" && options.WebhookURL == "" {
+		return nil, fmt.Errorf("audit log requires a file path or a webhook url")
+	}
+	logger := &Logger{
+		path:       options.Path,
+		maxBytes:   int64(options.MaxSizeMB) * 1024 * 1024,
+		maxBackups: options.MaxBackups,
+	}
+	if options.Path != "" {
+		if err := logger.open(); err != nil {
+			return nil, err
+		}
+	}
+	if options.WebhookURL != "" {
+		logger.webhook = newWebhookSink(options.WebhookURL)
+	}
+	return logger, nil
+}
+
+// Queryable 返回审计日志是否写入了文件
+func (l *Logger) Queryable() bool {
+	return l.path != ""
+}
+
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log %s: %w", l.path, err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Record 写入一条记录，写入失败只打印错误，不影响工具调用
+func (l *Logger) Record(entry Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to serialize audit entry for tool %s: %v\n", entry.Tool, err)
+		return
+	}
+	if l.webhook != nil {
+		l.webhook.send(line)
+	}
+	if l.path == "" {
+		return
+	}
+	line = append(line, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to rotate audit log: %v\n", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// rotate 把当前文件改名为 <path>.<时间>，然后删除超出数量的旧文件，调用方需要持有锁
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	backup := l.path + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(l.path, backup); err != nil {
+		return l.reopen(err)
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	if l.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := l.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > l.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// reopen 改名失败时继续写原来的文件
+func (l *Logger) reopen(cause error) error {
+	if err := l.open(); err != nil {
+		return fmt.Errorf("%v; %w", cause, err)
+	}
+	return cause
+}
+
+// backups 按时间从旧到新返回轮转出的文件
+func (l *Logger) backups() ([]string, error) {
+	matches, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	prefix := l.path + "."
+	for _, match := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(match, prefix)); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// Close 关闭文件，并等待webhook发送完队列中的记录
+func (l *Logger) Close() error {
+	if l.webhook != nil {
+		l.webhook.close()
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Filter 是查询审计日志的条件，空字段不过滤
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Tool    string
+	User    string
+	Session string
+	Outcome string
+	// Kind、Name、Namespace和Cluster匹配任意一个目标对象，Kind大小写不敏感
+	Kind      string
+	Name      string
+	Namespace string
+	Cluster   string
+	// ChangesOnly 只返回修改类工具的调用
+	ChangesOnly bool
+	// Limit 是返回的最大条数，保留最新的记录
+	Limit int
+}
+
+func (f Filter) matches(entry Entry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) ||
+		!f.Until.IsZero() && !entry.Time.Before(f.Until) ||
+		f.Tool != "" && entry.Tool != f.Tool ||
+		f.User != "" && entry.User != f.User ||
+		f.Session != "" && entry.Session != f.Session ||
+		f.Outcome != "" && entry.Outcome != f.Outcome ||
+		f.ChangesOnly && entry.ReadOnly {
+		return false
+	}
+	if f.Kind == "" && f.Name == "" && f.Namespace == "" && f.Cluster == "" {
+		return true
+	}
+	for _, target := range entry.Targets {
+		if (f.Kind == "" || strings.EqualFold(target.Kind, f.Kind)) &&
+			(f.Name == "" || target.Name == f.Name) &&
+			(f.Namespace == "" || target.Namespace == f.Namespace) &&
+			(f.Cluster == "" || target.Cluster == f.Cluster) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query 从旧到新读取轮转的文件和当前文件，按时间从新到旧返回匹配的记录
+func (l *Logger) Query(filter Filter) ([]Entry, error) {
+	if l.path == "" {
+		return nil, fmt.Errorf("audit log is not written to a file")
+	}
+	files, err := l.openForQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	var entries []Entry
+	for _, file := range files {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var entry Entry
+			// 跳过无法解析的行，例如正在写入的最后一行
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if !filter.matches(entry) {
+				continue
+			}
+			entries = append(entries, entry)
+			if filter.Limit > 0 && len(entries) > 2*filter.Limit {
+				entries = append(entries[:0], entries[len(entries)-filter.Limit:]...)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read audit log %s: %w", file.Name(), err)
+		}
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// openForQuery 持有锁打开所有文件，之后的轮转只会改名，不影响已经打开的文件
+func (l *Logger) openForQuery() ([]*os.File, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	paths, err := l.backups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log backups: %w", err)
+	}
+	paths = append(paths, l.path)
+	files := make([]*os.File, 0, len(paths))
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
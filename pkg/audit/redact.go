@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Redacted 替换被隐藏的值
+const Redacted = "[REDACTED]"
+
+// 参数名（忽略大小写）包含这些词时，参数的值会被隐藏
+var sensitiveArgumentWords = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "credential", "webhook", "authorization", "private_key", "privatekey"}
+
+// kubectl apply 保存的完整配置，其中可能有Secret的内容
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// RedactArguments 返回参数的脱敏副本，不修改原来的参数：
+// 名称敏感的参数整个隐藏；manifestArguments中的manifest保留结构，只隐藏Secret的data和stringData中的值
+func RedactArguments(arguments map[string]interface{}, manifestArguments ...string) map[string]interface{} {
+	if arguments == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		switch {
+		case sensitiveArgument(name):
+			redacted[name] = Redacted
+		case containsString(manifestArguments, name):
+			if manifest, ok := value.(string); ok {
+				redacted[name] = redactManifests(manifest)
+			} else {
+				redacted[name] = Redacted
+			}
+		default:
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
+
+func sensitiveArgument(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveArgumentWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// redactManifests 没有Secret时原样返回，否则返回隐藏了Secret内容的YAML；无法解析时整个隐藏
+func redactManifests(manifests string) string {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifests)))
+	var docs []map[string]interface{}
+	changed := false
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Redacted
+		}
+		jsonData, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return Redacted
+		}
+		if len(jsonData) == 0 || string(jsonData) == "null" {
+			continue
+		}
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(jsonData, &obj); err != nil {
+			return Redacted
+		}
+		if redactObject(obj) {
+			changed = true
+		}
+		docs = append(docs, obj)
+	}
+	if !changed {
+		return manifests
+	}
+	parts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return Redacted
+		}
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, "---\n")
+}
+
+// redactObject 隐藏Secret（包括List中的Secret）的内容，返回是否修改了对象
+func redactObject(obj map[string]interface{}) bool {
+	if items, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(stringField(obj, "kind"), "List") {
+		changed := false
+		for _, item := range items {
+			if itemObj, ok := item.(map[string]interface{}); ok && redactObject(itemObj) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	if stringField(obj, "kind") != "Secret" {
+		return false
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := obj[field].(map[string]interface{}); ok {
+			for key := range values {
+				values[key] = Redacted
+			}
+		}
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if _, ok := annotations[lastAppliedAnnotation]; ok {
+				annotations[lastAppliedAnnotation] = Redacted
+			}
+		}
+	}
+	return true
+}
+
+func stringField(obj map[string]interface{}, field string) string {
+	value, _ := obj[field].(string)
+	return value
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// 队列满时丢弃新的记录，而不是阻塞工具调用
+const webhookQueueSize = 1024
+
+// webhookSink 在后台逐条POST审计记录
+type webhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan []byte
+	done   chan struct{}
+	lock   sync.Mutex
+	closed bool
+}
+
+func newWebhookSink(url string) *webhookSink {
+	sink := &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan []byte, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go sink.run()
+	return sink
+}
+
+func (w *webhookSink) send(line []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.queue <- line:
+	default:
+		fmt.Fprintln(os.Stderr, "Warning: audit webhook queue is full, dropping entry")
+	}
+}
+
+func (w *webhookSink) run() {
+	defer close(w.done)
+	for line := range w.queue {
+		if err := w.post(line); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to send audit entry to webhook: %v\n", err)
+		}
+	}
+}
+
+func (w *webhookSink) post(line []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// close 最多等待30秒发送完队列中的记录
+func (w *webhookSink) close() {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+	select {
+	case <-w.done:
+	case <-time.After(30 * time.Second):
+		fmt.Fprintln(os.Stderr, "Warning: timed out sending queued audit entries to webhook")
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 认证方式，记录在Identity.Method中
+const (
+	MethodToken      = "token"
+	MethodJWT        = "jwt"
+	MethodClientCert = "client-cert"
+)
+
+// Identity 是认证得到的调用方身份
+type Identity struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+	Method string   `json:"method"`
+}
+
+// Authenticator 认证一个HTTP请求
+// 请求中没有这种方式的凭据时返回 nil, nil，交给下一个Authenticator；凭据无效时返回错误
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+type identityKey struct{}
+
+// WithIdentity 返回带有认证身份的context
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext 返回请求认证得到的身份，stdio模式或者没有启用认证时返回false
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// errNoCredentials 表示没有任何Authenticator认出请求中的凭据
+var errNoCredentials = errors.New("missing or unrecognized credentials")
+
+// Chain 按顺序尝试多个Authenticator，第一个认出凭据的决定结果
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (*Identity, error) {
+	for _, authenticator := range c {
+		identity, err := authenticator.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, errNoCredentials
+}
+
+// Wrap 返回先认证再调用next的Handler，认证失败时返回401，成功时身份放在请求的context中
+func (c Chain) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := c.Authenticate(r)
+		if err != nil {
+			fmt.Printf("Authentication failed for %s %s from %s: %v\n", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kube-mcp-server"`)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// bearerToken 返回Authorization头中的Bearer token
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
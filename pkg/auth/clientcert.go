@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ClientCert 使用已经通过TLS校验的客户端证书认证，和kube-apiserver一样CN是用户名，O是组
+type ClientCert struct{}
+
+// Authenticate 没有客户端证书时返回 nil, nil；证书链已经在TLS握手时用client CA校验过
+func (ClientCert) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("client certificate has no common name")
+	}
+	return &Identity{
+		User:   cert.Subject.CommonName,
+		Groups: cert.Subject.Organization,
+		Method: MethodClientCert,
+	}, nil
+}
+
+// ServerTLSConfig 返回HTTPS服务的TLS配置，clientCAFile不为空时校验客户端证书
+// requireClientCert为true时（只启用了mTLS）没有证书的连接在握手时就会被拒绝，否则证书是可选的，可以改用token认证
+func ServerTLSConfig(clientCAFile string, requireClientCert bool) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if requireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
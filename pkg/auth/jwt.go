@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 校验exp和nbf时允许的时钟偏差
+const jwtLeeway = time.Minute
+
+// JWKS中RSA公钥的最小长度
+const minRSAKeyBits = 2048
+
+// JWTOptions 是JWT/OIDC认证的配置，Audience必须设置，Issuer为空时不校验
+type JWTOptions struct {
+	// JWKSFile 是本地的JWKS文件，例如从OIDC提供方的jwks_uri下载的内容
+	JWKSFile string
+	Issuer   string
+	Audience string
+	// UsernameClaim 默认为sub，GroupsClaim默认为groups
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// jwk 是JWKS中的一个公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// JWTAuthenticator 使用本地JWKS中的公钥校验Bearer token的签名，然后校验iss、aud、exp和nbf
+// 支持RS*、PS*、ES*和EdDSA，不支持none和HMAC
+type JWTAuthenticator struct {
+	options JWTOptions
+	keys    []verificationKey
+}
+
+// NewJWTAuthenticator 读取JWKS文件
+func NewJWTAuthenticator(options JWTOptions) (*JWTAuthenticator, error) {
+	// 不校验aud时，同一个提供方签发给其他服务的token也能通过认证
+	if options.Audience == "" {
+		return nil, fmt.Errorf("JWT authentication requires an audience")
+	}
+	if options.UsernameClaim == "" {
+		options.UsernameClaim = "sub"
+	}
+	if options.GroupsClaim == "" {
+		options.GroupsClaim = "groups"
+	}
+	data, err := os.ReadFile(options.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", options.JWKSFile, err)
+	}
+	authenticator := &JWTAuthenticator{options: options}
+	for i, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS file %s key %d (kid %q): %w", options.JWKSFile, i, key.Kid, err)
+		}
+		authenticator.keys = append(authenticator.keys, verificationKey{kid: key.Kid, alg: key.Alg, key: publicKey})
+	}
+	if len(authenticator.keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s contains no signing keys", options.JWKSFile)
+	}
+	return authenticator, nil
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid e")
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key is %d bits, at least %d bits are required", modulus.BitLen(), minRSAKeyBits)
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid coordinate length for curve %s", k.Crv)
+		}
+		key, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s public key: %w", k.Crv, err)
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid x")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Authenticate 不像JWT的token（不是三段）返回 nil, nil，留给其他方式
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+	claims, err := a.verify(token, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return a.identity(claims)
+}
+
+// verify 校验签名和标准的claim，返回所有claim
+func (a *JWTAuthenticator) verify(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	headerData, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	verified := false
+	for _, key := range a.keys {
+		if header.Kid != "" && key.kid != "" && key.kid != header.Kid {
+			continue
+		}
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		if err := verifySignature(header.Alg, key.key, signed, signature); err == nil {
+			verified = true
+			break
+		} else if _, unsupported := err.(unsupportedAlgorithmError); unsupported {
+			return nil, err
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("signature verification failed")
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	claims := map[string]interface{}{}
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, fmt.Errorf("exp claim is required")
+	}
+	if now.After(exp.Add(jwtLeeway)) {
+		return nil, fmt.Errorf("token expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(jwtLeeway).Before(nbf) {
+		return nil, fmt.Errorf("token is not valid before %s", nbf.UTC().Format(time.RFC3339))
+	}
+	if a.options.Issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != a.options.Issuer {
+			return nil, fmt.Errorf("unexpected issuer %q", issuer)
+		}
+	}
+	if a.options.Audience != "" && !containsString(stringList(claims["aud"]), a.options.Audience) {
+		return nil, fmt.Errorf("token audience does not include %q", a.options.Audience)
+	}
+	return claims, nil
+}
+
+func (a *JWTAuthenticator) identity(claims map[string]interface{}) (*Identity, error) {
+	user, _ := claims[a.options.UsernameClaim].(string)
+	if user == "" {
+		return nil, fmt.Errorf("invalid token: claim %q is missing", a.options.UsernameClaim)
+	}
+	return &Identity{User: user, Groups: stringList(claims[a.options.GroupsClaim]), Method: MethodJWT}, nil
+}
+
+// numericDate 解析exp、nbf这样的NumericDate
+func numericDate(value interface{}) (time.Time, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// stringList 读取可以是单个字符串或字符串数组的claim
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type unsupportedAlgorithmError string
+
+func (e unsupportedAlgorithmError) Error() string {
+	return fmt.Sprintf("unsupported signing algorithm %q", string(e))
+}
+
+// ES*算法对应的曲线
+var ecdsaCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// verifySignature 校验JWS签名，key的类型（ES*还包括曲线）和算法不匹配时返回错误
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match %s", alg)
+		}
+		if !ed25519.Verify(edKey, signed, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return unsupportedAlgorithmError(alg)
+	}
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match %s", alg)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+	case "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match %s", alg)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, digest, signature, nil)
+	default:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != ecdsaCurves[alg] {
+			return fmt.Errorf("key type does not match %s", alg)
+		}
+		// JWS中的ECDSA签名是定长的r||s
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid, crv string, key *ecdsa.PublicKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": crv,
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// signToken 用signer对header.payload签名，signer为nil时签名为空
+func signToken(t *testing.T, header, claims map[string]interface{}, signer func(signed []byte) []byte) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	var signature []byte
+	if signer != nil {
+		signature = signer([]byte(signed))
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func rsaSigner(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+}
+
+func ecdsaSigner(t *testing.T, key *ecdsa.PrivateKey, hash crypto.Hash) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		hasher := hash.New()
+		hasher.Write(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, key, hasher.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	}
+}
+
+func TestJWTAuthenticatorVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublic := rsaJWK("rsa", &rsaKey.PublicKey)
+	authenticator, err := NewJWTAuthenticator(JWTOptions{
+		JWKSFile: writeJWKS(t, rsaPublic, ecJWK("ec", "P-256", &p256Key.PublicKey)),
+		Issuer:   "https://issuer.example.com",
+		Audience: "kube-mcp-server",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{
+			"iss": "https://issuer.example.com",
+			"aud": []string{"kube-mcp-server"},
+			"sub": "alice",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for name, value := range overrides {
+			result[name] = value
+		}
+		return result
+	}
+	// HMAC密钥使用RSA公钥的内容，验证方把公钥当成HMAC密钥时签名就能通过
+	hmacSigner := func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(rsaPublic["n"]))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+	parts := strings.Split(signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(nil), rsaSigner(t, rsaKey)), ".")
+	tampered := parts[0] + "." + encodeSegment(t, claims(map[string]interface{}{"sub": "admin"})) + "." + parts[2]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid RS256", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(nil), rsaSigner(t, rsaKey)), ""},
+		{"valid ES256", signToken(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, claims(nil), ecdsaSigner(t, p256Key, crypto.SHA256)), ""},
+		{"valid without kid", signToken(t, map[string]interface{}{"alg": "RS256"}, claims(nil), rsaSigner(t, rsaKey)), ""},
+		{"expired", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(map[string]interface{}{"exp": now.Add(-2 * jwtLeeway).Unix()}), rsaSigner(t, rsaKey)), "expired"},
+		{"not yet valid", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(map[string]interface{}{"nbf": now.Add(2 * jwtLeeway).Unix()}), rsaSigner(t, rsaKey)), "not valid before"},
+		{"missing exp", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(map[string]interface{}{"exp": nil}), rsaSigner(t, rsaKey)), "exp claim is required"},
+		{"wrong audience", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(map[string]interface{}{"aud": "other-service"}), rsaSigner(t, rsaKey)), "audience"},
+		{"wrong issuer", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(map[string]interface{}{"iss": "https://other.example.com"}), rsaSigner(t, rsaKey)), "issuer"},
+		{"wrong kid", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "unknown"}, claims(nil), rsaSigner(t, rsaKey)), "signature verification failed"},
+		{"tampered payload", tampered, "signature verification failed"},
+		{"alg none", signToken(t, map[string]interface{}{"alg": "none", "kid": "rsa"}, claims(nil), nil), "unsupported signing algorithm"},
+		{"HS256 with the public key as secret", signToken(t, map[string]interface{}{"alg": "HS256", "kid": "rsa"}, claims(nil), hmacSigner), "unsupported signing algorithm"},
+		{"RS256 header with the EC key", signToken(t, map[string]interface{}{"alg": "RS256", "kid": "ec"}, claims(nil), ecdsaSigner(t, p256Key, crypto.SHA256)), "signature verification failed"},
+		{"ES256 header with the RSA key", signToken(t, map[string]interface{}{"alg": "ES256", "kid": "rsa"}, claims(nil), rsaSigner(t, rsaKey)), "signature verification failed"},
+		{"ES384 header with a P-256 key", signToken(t, map[string]interface{}{"alg": "ES384", "kid": "ec"}, claims(nil), ecdsaSigner(t, p256Key, crypto.SHA384)), "signature verification failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := authenticator.verify(tt.token, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify() error = %v", err)
+				}
+				if result["sub"] != "alice" {
+					t.Errorf("verify() sub = %v, want alice", result["sub"])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verify() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewJWTAuthenticatorRejectsWeakConfiguration(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strongKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		options JWTOptions
+		wantErr string
+	}{
+		{"RSA key shorter than 2048 bits", JWTOptions{JWKSFile: writeJWKS(t, rsaJWK("weak", &weakKey.PublicKey)), Audience: "kube-mcp-server"}, "at least 2048 bits"},
+		{"missing audience", JWTOptions{JWKSFile: writeJWKS(t, rsaJWK("strong", &strongKey.PublicKey))}, "requires an audience"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewJWTAuthenticator(tt.options); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewJWTAuthenticator() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// staticToken 是token文件中的一行，只保存token的摘要
+type staticToken struct {
+	digest   [sha256.Size]byte
+	identity Identity
+}
+
+// StaticTokens 使用和kube-apiserver --token-auth-file相同格式的文件认证Bearer token：
+// 每行 token,user,uid,"group1,group2"，uid和groups可以省略，# 开头的行是注释
+type StaticTokens struct {
+	tokens []staticToken
+}
+
+// LoadStaticTokens 读取token文件
+func LoadStaticTokens(path string) (*StaticTokens, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	result := &StaticTokens{}
+	seen := map[[sha256.Size]byte]bool{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse token file %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("token file %s line %d: token and user are required", path, line)
+		}
+		token := staticToken{
+			digest:   sha256.Sum256([]byte(record[0])),
+			identity: Identity{User: record[1], Method: MethodToken},
+		}
+		if seen[token.digest] {
+			return nil, fmt.Errorf("token file %s line %d: duplicate token", path, line)
+		}
+		seen[token.digest] = true
+		if len(record) > 3 {
+			for _, group := range strings.Split(record[3], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					token.identity.Groups = append(token.identity.Groups, group)
+				}
+			}
+		}
+		result.tokens = append(result.tokens, token)
+	}
+	if len(result.tokens) == 0 {
+		return nil, fmt.Errorf("token file %s contains no tokens", path)
+	}
+	return result, nil
+}
+
+// Authenticate 文件中没有这个token时返回 nil, nil，JWT认证还可以继续尝试
+// 比较摘要时使用常量时间比较，并且总是比较所有的token
+func (s *StaticTokens) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	digest := sha256.Sum256([]byte(token))
+	var matched *Identity
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare(digest[:], s.tokens[i].digest[:]) == 1 {
+			matched = &s.tokens[i].identity
+		}
+	}
+	if matched == nil {
+		return nil, nil
+	}
+	identity := *matched
+	return &identity, nil
+}
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// server-side apply 默认使用的field manager
+const DefaultFieldManager = "kube-mcp-server"
+
+// ApplyOptions 控制server-side apply的行为
+type ApplyOptions struct {
+	// DryRun 为true时只让API Server计算apply的结果，不持久化
+	DryRun bool
+	// Force 为true时强制接管被其他field manager管理的冲突字段
+	Force bool
+	// FieldManager 为空时使用DefaultFieldManager
+	FieldManager string
+}
+
+// applyObject 使用server-side apply创建或更新一个对象
+// 返回值中包含apply前的线上对象与API Server计算出的结果之间的差异(diff)
+// 这样可以先用dryRun预览变更，确认后再真正写入
+func (c *Client) applyObject(ctx context.Context, namespace, kind string, obj *unstructured.Unstructured, opts ApplyOptions) (map[string]interface{}, error) {
+	var target *apiResource
+	var err error
+	switch {
+	// manifest中带有apiVersion和kind时按它们解析，避免同名Kind解析到其他group
+	case obj.GetAPIVersion() != "" && obj.GetKind() != "":
+		target, err = c.resolveGVK(obj.GetAPIVersion(), obj.GetKind())
+	case kind != "":
+		target, err = c.resolveAPIResource(kind)
+	case obj.GetKind() != "":
+		target, err = c.resolveAPIResource(obj.GetKind())
+	default:
+		return nil, fmt.Errorf("resources is required ,either provide it as a parameter or include it in the manifest")
+	}
+	if err != nil {
+		return nil, err
+	}
+	gvr := &target.gvr
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("resource name is required in manifest")
+	}
+	// server-side apply 要求manifest中带有apiVersion和kind
+	if obj.GetKind() == "" {
+		obj.SetKind(target.kind)
+	}
+	if obj.GetAPIVersion() == "" {
+		obj.SetAPIVersion(gvr.GroupVersion().String())
+	}
+	fieldManager := opts.FieldManager
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+
+	var resource dynamic.ResourceInterface
+	namespaceMissing := false
+	if target.namespaced {
+		if namespace != "" {
+			obj.SetNamespace(namespace)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
+		}
+		namespaceMissing, err = c.ensureNamespace(ctx, obj.GetNamespace(), opts.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		resource = c.dynamicClient.Resource(*gvr).Namespace(obj.GetNamespace())
+	} else {
+		obj.SetNamespace("")
+		resource = c.dynamicClient.Resource(*gvr)
+	}
+
+	response := map[string]interface{}{
+		"kind":         obj.GetKind(),
+		"name":         obj.GetName(),
+		"namespace":    obj.GetNamespace(),
+		"dryRun":       opts.DryRun,
+		"fieldManager": fieldManager,
+	}
+
+	// 命名空间不存在时API Server无法dry-run，只能和提交的manifest做比较
+	if namespaceMissing && opts.DryRun {
+		response["operation"] = "created"
+		response["namespaceCreated"] = true
+		response["diff"] = DiffObjects(nil, obj.UnstructuredContent())
+		response["warning"] = fmt.Sprintf("namespace %s does not exist and will be created, the diff is computed from the manifest instead of the server", obj.GetNamespace())
+		return response, nil
+	}
+
+	// 写入失败时删除这次调用创建的命名空间，不留下空的命名空间
+	namespaceCreated := namespaceMissing && !opts.DryRun
+	var liveContent map[string]interface{}
+	live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		liveContent = live.UnstructuredContent()
+	} else if !errors.IsNotFound(err) {
+		err = fmt.Errorf("failed to get live resource: %w", err)
+		if namespaceCreated {
+			err = c.deleteCreatedNamespace(ctx, obj.GetNamespace(), err)
+		}
+		return nil, err
+	}
+
+	applyOptions := metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        opts.Force,
+	}
+	if opts.DryRun {
+		applyOptions.DryRun = []string{metav1.DryRunAll}
+	}
+	result, err := resource.Apply(ctx, obj.GetName(), obj, applyOptions)
+	if err != nil {
+		if errors.IsConflict(err) {
+			err = fmt.Errorf("server-side apply conflict, the fields are owned by another field manager, retry with force=true to take ownership: %w", err)
+		} else {
+			err = fmt.Errorf("failed to apply resource: %w", err)
+		}
+		if namespaceCreated {
+			err = c.deleteCreatedNamespace(ctx, obj.GetNamespace(), err)
+		}
+		return nil, err
+	}
+
+	diff := DiffObjects(liveContent, result.UnstructuredContent())
+	switch {
+	case liveContent == nil:
+		response["operation"] = "created"
+	case len(diff) == 0:
+		response["operation"] = "unchanged"
+	default:
+		response["operation"] = "configured"
+	}
+	if namespaceMissing {
+		response["namespaceCreated"] = true
+	}
+	response["diff"] = diff
+	response["resourceVersion"] = result.GetResourceVersion()
+	content := result.UnstructuredContent()
+	unstructured.RemoveNestedField(content, "metadata", "managedFields")
+	response["object"] = content
+	return response, nil
+}
+
+// ensureNamespace 确认命名空间存在，不存在时创建它（dryRun时不创建）
+// 返回值表示命名空间原本不存在，并且是这次调用创建的（dryRun时是将要创建）
+func (c *Client) ensureNamespace(ctx context.Context, namespace string, dryRun bool) (bool, error) {
+	_, err := c.Clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get namespace %s:%w", namespace, err)
+	}
+	if dryRun {
+		return true, nil
+	}
+	_, err = c.Clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"kubernetes.io/metadata.name": namespace,
+			},
+			Name: namespace,
+		},
+	}, metav1.CreateOptions{FieldManager: DefaultFieldManager})
+	if errors.IsAlreadyExists(err) {
+		// 同时有其他调用创建了它，失败时不能由这次调用删除
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create namespace %s:%w", namespace, err)
+	}
+	return true, nil
+}
+
+// deleteCreatedNamespace 在写入失败后删除ensureNamespace刚创建的命名空间，返回写入的错误，删除失败时附加删除的错误
+func (c *Client) deleteCreatedNamespace(ctx context.Context, namespace string, cause error) error {
+	err := c.Clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("%w; failed to delete namespace %s created for this apply: %v", cause, namespace, err)
+	}
+	return cause
+}
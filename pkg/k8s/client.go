@@ -1,21 +1,20 @@
 package k8s
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -46,11 +45,15 @@ type Client struct {
 	restConfig             *rest.Config
 	informerFactory        informers.SharedInformerFactory
 	dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
-	apiResourceCache       map[string]*schema.GroupVersionResource
+	apiResources           map[string]*apiResource
 	resourceCaches         map[string]cache.Store
+	resourceInformers      map[string]cache.SharedIndexInformer
 	informerSynced         map[string]cache.InformerSynced
 	informerLock           sync.RWMutex
 	cacheLock              sync.RWMutex
+	// base和identity只在模拟其他身份的客户端中设置，见newImpersonatedClient
+	base     *Client
+	identity Identity
 }
 
 // event 事件处理
@@ -172,28 +175,15 @@ func (c *Client) autoRegisterAllInformers() error {
 		return fmt.Errorf("获取API资源失败: %w", err)
 	}
 
-	for _, resourceGroup := range resourcesList {
-		gv, err := schema.ParseGroupVersion(resourceGroup.GroupVersion)
-		if err != nil {
+	// 2. 为支持list/watch的资源注册Informer，缓存使用resolver分配的key
+	for _, resource := range c.registerAPIResources(resourcesList) {
+		if !c.supportsListAndWatchVerbs(resource.verbs) {
 			continue
 		}
-
-		for _, resource := range resourceGroup.APIResources {
-			gvr := schema.GroupVersionResource{
-				Group:    gv.Group,
-				Version:  gv.Version,
-				Resource: resource.Name,
-			}
-
-			if !c.supportsListAndWatchVerbs(resource.Verbs) {
-				continue
-			}
-
-			informer := c.dynamicInformerFactory.ForResource(gvr).Informer()
-			c.resourceCaches[resource.Kind] = informer.GetStore()
-			c.informerSynced[resource.Kind] = informer.HasSynced
-			c.apiResourceCache[resource.Kind] = &gvr
-		}
+		informer := c.dynamicInformerFactory.ForResource(resource.gvr).Informer()
+		c.resourceCaches[resource.key] = informer.GetStore()
+		c.resourceInformers[resource.key] = informer
+		c.informerSynced[resource.key] = informer.HasSynced
 	}
 
 	return nil
@@ -219,6 +209,26 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewClientForConfig(config)
+}
+
+// NewClientForConfig 使用给定的rest config构建客户端，并为集群中所有可list/watch的资源注册Informer
+// Informer需要调用StartInformers之后才会开始工作
+func NewClientForConfig(config *rest.Config) (*Client, error) {
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	client.dynamicInformerFactory = dynamicinformer.NewDynamicSharedInformerFactory(client.dynamicClient, 30*time.Second)
+	if err := client.autoRegisterAllInformers(); err != nil {
+		return nil, fmt.Errorf("自动注册Informer失败: %w", err)
+	}
+
+	return client, nil
+}
+
+// newClient 只构建各种客户端，不注册Informer
+func newClient(config *rest.Config) (*Client, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("构建clientset失败 %w", err)
@@ -236,27 +246,19 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 		return nil, fmt.Errorf("构建metricsClient失败 %w", err)
 	}
 
-	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 30*time.Second)
-
-	client := &Client{
-		Clientset:              clientset,
-		dynamicClient:          dynamicClient,
-		discoveryClient:        discoveryClient,
-		metricsClient:          metricsClient,
-		restConfig:             config,
-		dynamicInformerFactory: dynamicInformerFactory,
-		apiResourceCache:       make(map[string]*schema.GroupVersionResource),
-		resourceCaches:         make(map[string]cache.Store),
-		informerSynced:         make(map[string]cache.InformerSynced),
-		cacheLock:              sync.RWMutex{},
-		informerLock:           sync.RWMutex{},
-	}
-
-	if err := client.autoRegisterAllInformers(); err != nil {
-		return nil, fmt.Errorf("自动注册Informer失败: %w", err)
-	}
-
-	return client, nil
+	return &Client{
+		Clientset:         clientset,
+		dynamicClient:     dynamicClient,
+		discoveryClient:   discoveryClient,
+		metricsClient:     metricsClient,
+		restConfig:        config,
+		apiResources:      make(map[string]*apiResource),
+		resourceCaches:    make(map[string]cache.Store),
+		resourceInformers: make(map[string]cache.SharedIndexInformer),
+		informerSynced:    make(map[string]cache.InformerSynced),
+		cacheLock:         sync.RWMutex{},
+		informerLock:      sync.RWMutex{},
+	}, nil
 }
 
 // 列出所有的在集群中的资源类型
@@ -306,53 +308,45 @@ func (c *Client) WaitForCacheSync(ctx context.Context) bool {
 	return true
 }
 
-// getResourceCacheKey 生成资源缓存的key，格式为 "kind/namespace/name" 或 "kind/name"（集群资源）
+// getResourceCacheKey 生成资源缓存的key，每种kind有自己的store，key和informer使用的
+// cache.MetaNamespaceKeyFunc 一致，格式为 "namespace/name" 或 "name"（集群资源）
 func (c *Client) getResourceCacheKey(kind, namespace, name string) string {
 	if namespace != "" {
-		return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+		return fmt.Sprintf("%s/%s", namespace, name)
 	}
-	return fmt.Sprintf("%s/%s", kind, name)
+	return name
 }
 
-// getCachedGVR 用来获取GVR ，通过输入kind的方式，如果catch中有就直接从中获取，如果没有就先写入在获取
+// getCachedGVR 用来获取GVR，kind可以是Kind、复数、单数、简称或者带group的写法，解析规则见resolveAPIResource
 // 通过gvr可以方便的使用dynamicClient来进行资源的增删改等
 func (c *Client) getCachedGVR(kind string) (*schema.GroupVersionResource, error) {
-	c.cacheLock.RLock()
-	if gvr, exists := c.apiResourceCache[kind]; exists {
-		c.cacheLock.RUnlock()
-		return gvr, nil
-	}
-	c.cacheLock.RUnlock()
-	//cache miss,从discovery client获取
-	resourceLists, err := c.discoveryClient.ServerPreferredResources()
-	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
-		return nil, fmt.Errorf("failed to retrieve api resource:%w", err)
-	}
-	for _, resourceList := range resourceLists {
-		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
-		if err != nil {
-			continue
-		}
-		for _, resource := range resourceList.APIResources {
-			if resource.Kind == kind {
-				gvr := &schema.GroupVersionResource{
-					Group:    gv.Group,
-					Version:  gv.Version,
-					Resource: resource.Name,
-				}
-				c.cacheLock.Lock()
-				c.apiResourceCache[kind] = gvr
-				c.cacheLock.Unlock()
-				return gvr, nil
-			}
-		}
+	r, err := c.resolveAPIResource(kind)
+	if err != nil {
+		return nil, err
 	}
+	gvr := r.gvr
+	return &gvr, nil
+}
+
+// isNamespaced 判断kind对应的资源是否属于命名空间
+// 未知的kind按命名空间级别的资源处理
+func (c *Client) isNamespaced(kind string) bool {
+	r, err := c.lookupAPIResource(kind)
+	return err != nil || r == nil || r.namespaced
+}
 
-	return nil, fmt.Errorf("resource type %s not found", kind)
+// canonicalKind 返回kind对应的Kind名称，例如 deploy -> Deployment，无法解析时原样返回
+func (c *Client) canonicalKind(kind string) string {
+	r, err := c.lookupAPIResource(kind)
+	if err != nil || r == nil {
+		return kind
+	}
+	return r.kind
 }
 
 // getResourceFromCache 从本地缓存获取资源
 func (c *Client) getResourceFromCache(kind, namespace, name string) (map[string]interface{}, bool) {
+	kind = c.resourceKey(kind)
 	cacheKey := c.getResourceCacheKey(kind, namespace, name)
 	c.informerLock.RLock()
 	defer c.informerLock.RUnlock()
@@ -368,28 +362,52 @@ func (c *Client) getResourceFromCache(kind, namespace, name string) (map[string]
 }
 
 // listResourcesFromCache 从本地缓存列出资源
-func (c *Client) listResourcesFromCache(kind, namespace, labelSelector, fieldSelector string) ([]map[string]interface{}, bool) {
+// 标签选择器和常用的字段选择器在本地计算，包含无法本地计算的字段选择器时返回false，由调用方请求API Server
+func (c *Client) listResourcesFromCache(kind, namespace, labelSelector, fieldSelector string) ([]*unstructured.Unstructured, bool) {
+	labelSel, err := labels.Parse(labelSelector)
+	if err != nil {
+		// 交给API Server返回错误
+		return nil, false
+	}
+	kind = c.resourceKey(kind)
+	fieldSel, ok := parseCachedFieldSelector(kind, fieldSelector)
+	if !ok {
+		return nil, false
+	}
+
 	c.informerLock.RLock()
 	defer c.informerLock.RUnlock()
 
-	if cache, exists := c.resourceCaches[kind]; exists {
-		items := cache.List()
-		var result []map[string]interface{}
+	if store, exists := c.resourceCaches[kind]; exists {
+		// 缓存还没有同步完成时，列出的结果是不完整的
+		if synced, ok := c.informerSynced[kind]; ok && !synced() {
+			return nil, false
+		}
+		var items []interface{}
+		indexer, isIndexer := store.(cache.Indexer)
+		if namespace != "" && isIndexer {
+			if items, err = indexer.ByIndex(cache.NamespaceIndex, namespace); err != nil {
+				items = store.List()
+			}
+		} else {
+			items = store.List()
+		}
+		result := []*unstructured.Unstructured{}
 		for _, item := range items {
-			if metaObj, ok := item.(metav1.Object); ok {
-				if namespace != "" && metaObj.GetNamespace() != namespace {
-					continue
-				}
-				if labelSelector != "" || fieldSelector != "" {
-					return nil, false
-				}
-				result = append(result, map[string]interface{}{
-					"name":      metaObj.GetName(),
-					"kind":      kind,
-					"namespace": metaObj.GetNamespace(),
-					"lables":    metaObj.GetLabels(),
-				})
+			obj, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if namespace != "" && obj.GetNamespace() != namespace {
+				continue
+			}
+			if !labelSel.Matches(labels.Set(obj.GetLabels())) {
+				continue
 			}
+			if !fieldSel.Matches(cachedFieldSet(kind, obj)) {
+				continue
+			}
+			result = append(result, obj)
 		}
 		return result, true
 	}
@@ -424,10 +442,32 @@ func (c *Client) GetResource(ctx context.Context, kind, name, namespace string)
 	return obj.UnstructuredContent(), nil
 }
 
-func (c *Client) ListResources(ctx context.Context, kind, namespace, labelSelector, fieldSelector string) ([]map[string]interface{}, error) {
+// ListResources 列出某种资源，page.Limit大于0时分页返回，projection为零值时每个对象只返回名称和标签
+// 优先从本地缓存获取，缓存分页和API Server分页的continue token不能混用
+func (c *Client) ListResources(ctx context.Context, kind, namespace, labelSelector, fieldSelector string, page ListPage, projection Projection) (*ListResult, error) {
+	token, err := decodeListToken(page.Continue)
+	if err != nil {
+		return nil, err
+	}
 	// 首先尝试从本地缓存获取
-	if resources, found := c.listResourcesFromCache(kind, namespace, labelSelector, fieldSelector); found {
-		return resources, nil
+	if token == nil || token.Source == listSourceCache {
+		if resources, found := c.listResourcesFromCache(kind, namespace, labelSelector, fieldSelector); found {
+			after := ""
+			if token != nil {
+				after = token.After
+			}
+			objects, total, next := paginateCached(resources, page, after)
+			result, err := buildListResult(c.canonicalKind(kind), objects, projection)
+			if err != nil {
+				return nil, err
+			}
+			result.Total = &total
+			result.Continue = next
+			return result, nil
+		}
+		if token != nil {
+			return nil, fmt.Errorf("the continue token is no longer valid, list again without it")
+		}
 	}
 
 	// 缓存未命中或有复杂选择器，调用API Server
@@ -438,6 +478,12 @@ func (c *Client) ListResources(ctx context.Context, kind, namespace, labelSelect
 	options := metav1.ListOptions{
 		LabelSelector: labelSelector,
 		FieldSelector: fieldSelector,
+		Limit:         page.Limit,
+	}
+	var offset int64
+	if token != nil {
+		options.Continue = token.Token
+		offset = token.Offset
 	}
 	var list *unstructured.UnstructuredList
 	if namespace != "" {
@@ -448,88 +494,41 @@ func (c *Client) ListResources(ctx context.Context, kind, namespace, labelSelect
 	if err != nil {
 		return nil, fmt.Errorf("failed to list resources%w", err)
 	}
-	var resources []map[string]interface{}
-	for _, item := range list.Items {
-		metadata := item.GetLabels()
-		resources = append(resources, map[string]interface{}{
-			"name":      item.GetName(),
-			"kind":      item.GetKind(),
-			"namespace": item.GetNamespace(),
-			"lables":    metadata,
-		})
+	objects := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
 	}
-	return resources, err
-
+	result, err := buildListResult(c.canonicalKind(kind), objects, projection)
+	if err != nil {
+		return nil, err
+	}
+	returned := offset + int64(len(objects))
+	if list.GetContinue() == "" {
+		result.Total = &returned
+	} else {
+		// 带有选择器时API Server不会返回剩余数量
+		if remaining := list.GetRemainingItemCount(); remaining != nil {
+			total := returned + *remaining
+			result.Total = &total
+		}
+		result.Continue = encodeListToken(listToken{Source: listSourceAPI, Offset: returned, Token: list.GetContinue()})
+	}
+	return result, nil
 }
 
-// 通过manifest的方式创建或者更新一个资源，创建成功会返回对应资源的结构
-func (c *Client) CreateOrUpdateResoureceJSON(ctx context.Context, namespace, manifestJSON, kind string) (map[string]interface{}, error) {
+// 通过JSON manifest的方式创建或者更新一个资源，使用server-side apply
+// 返回本次apply的结果以及与线上对象的差异，dryRun时不会持久化任何变更
+func (c *Client) CreateOrUpdateResoureceJSON(ctx context.Context, namespace, manifestJSON, kind string, opts ApplyOptions) (map[string]interface{}, error) {
 	obj := &unstructured.Unstructured{}
 	if err := json.Unmarshal([]byte(manifestJSON), &obj.Object); err != nil {
 		return nil, fmt.Errorf("failed to parse resourfce manifest JSON %w", err)
 	}
-	//获取资源gvr
-	gvr, err := c.getCachedGVR(kind)
-	if err == nil {
-		return nil, err
-	}
-	//看对应的ns是否存在
-	_, err = c.Clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
-	if err == nil {
-		fmt.Printf("namespace %s exists\n", namespace)
-	}
-	if errors.IsNotFound(err) {
-		fmt.Printf("Namespace %s does not exist,creating one\n", namespace)
-		_, err = c.Clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					"kubernetes.io/metadata.name": namespace,
-				},
-				Name: namespace,
-			},
-			Spec: corev1.NamespaceSpec{
-				Finalizers: []corev1.FinalizerName{
-					corev1.FinalizerKubernetes,
-				},
-			},
-			Status: corev1.NamespaceStatus{
-				Phase:      corev1.NamespaceActive,
-				Conditions: nil,
-			},
-		}, metav1.CreateOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create namespace %s:%w", namespace, err)
-		}
-	}
-
-	obj.SetNamespace(namespace)
-	if obj.GetName() == "" {
-		return nil, fmt.Errorf("resource name is requird")
-	}
-	resource := c.dynamicClient.Resource(*gvr).Namespace(obj.GetNamespace())
-	rawJSON := []byte(manifestJSON)
-	//直接尝试更新
-	result, err := resource.Patch(
-		ctx,
-		obj.GetName(),
-		types.MergePatchType,
-		rawJSON,
-		metav1.PatchOptions{},
-	)
-	//说明没有资源需要创建
-	if errors.IsNotFound(err) {
-		result, err := resource.Create(ctx, obj, metav1.CreateOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("falied to create or patch resopurce:%w", err)
-		}
-		return result.UnstructuredContent(), nil
-	}
-	return result.UnstructuredContent(), nil
+	return c.applyObject(ctx, namespace, kind, obj, opts)
 }
 
-// CreateOrUpdateResourceYAML 用创建一个新资源
-// 先将yaml转换为json，然后使用CreateOrUpdateJSON
-func (c *Client) CreateOrUpdateResourceYAML(ctx context.Context, namespace, yamlManifest, kind string) (map[string]interface{}, error) {
+// CreateOrUpdateResourceYAML 用YAML manifest创建或者更新一个资源
+// 先将yaml转换为json，然后和CreateOrUpdateResoureceJSON一样使用server-side apply
+func (c *Client) CreateOrUpdateResourceYAML(ctx context.Context, namespace, yamlManifest, kind string, opts ApplyOptions) (map[string]interface{}, error) {
 	jsonData, err := yaml.YAMLToJSON([]byte(yamlManifest))
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve yaml manifest:%w", err)
@@ -539,139 +538,156 @@ func (c *Client) CreateOrUpdateResourceYAML(ctx context.Context, namespace, yaml
 	if err := json.Unmarshal(jsonData, &obj.Object); err != nil {
 		return nil, fmt.Errorf("failed to parse converted JSON From manifest:%w", err)
 	}
-	resourceKind := kind
-	if resourceKind == "" {
-		resourceKind = obj.GetKind()
-		if resourceKind == "" {
-			return nil, fmt.Errorf("resources is required ,either provide it as a parameter or include it in the YAML manifest")
-		}
-	}
-	gvr, err := c.getCachedGVR(resourceKind)
+	return c.applyObject(ctx, namespace, kind, obj, opts)
+}
+
+// DeleteResource 删除对象，dryRun为true时只在API Server上校验，不会真正删除
+// 返回被删除的对象，以及垃圾回收会随之删除的子对象（例如Deployment的ReplicaSet和Pod）
+func (c *Client) DeleteResource(ctx context.Context, kind, name, namespace string, dryRun bool) (map[string]interface{}, error) {
+	resource, err := c.resolveAPIResource(kind)
 	if err != nil {
 		return nil, err
 	}
-	//看对应的ns是否存在
-	_, err = c.Clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
-	if err == nil {
-		fmt.Printf("namespace %s exists\n", namespace)
-	}
-	if errors.IsNotFound(err) {
-		fmt.Printf("Namespace %s does not exist,creating one\n", namespace)
-		_, err = c.Clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					"kubernetes.io/metadata.name": namespace,
-				},
-				Name: namespace,
-			},
-			Spec: corev1.NamespaceSpec{
-				Finalizers: []corev1.FinalizerName{
-					corev1.FinalizerKubernetes,
-				},
-			},
-			Status: corev1.NamespaceStatus{
-				Phase:      corev1.NamespaceActive,
-				Conditions: nil,
-			},
-		}, metav1.CreateOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create namespace %s:%w", namespace, err)
+	if !resource.namespaced {
+		namespace = ""
+	}
+	// 真正删除时调用方只需要知道是否成功，不再读取对象和子对象
+	if !dryRun {
+		if err := c.deleteObject(ctx, resource, namespace, name, metav1.DeleteOptions{}); err != nil {
+			return nil, err
 		}
+		return map[string]interface{}{
+			"kind":      resource.kind,
+			"name":      name,
+			"namespace": namespace,
+			"dryRun":    false,
+		}, nil
 	}
 
-	if namespace != "" {
-		obj.SetNamespace(namespace)
-	}
-	if obj.GetName() == "" {
-		return nil, fmt.Errorf("resource name is required in YAML manifest")
-	}
-	resource := c.dynamicClient.Resource(*gvr).Namespace(obj.GetNamespace())
-	result, err := resource.Patch(
-		ctx,
-		obj.GetName(),
-		types.MergePatchType,
-		jsonData,
-		metav1.PatchOptions{},
-	)
-	if errors.IsNotFound(err) {
-		result, err = resource.Create(ctx, obj, metav1.CreateOptions{})
-	}
+	obj, err := c.getObject(ctx, resource, namespace, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create or patch resource from YAML manifest: %w", err)
+		return nil, err
 	}
-
-	return result.UnstructuredContent(), nil
+	if err := c.deleteObject(ctx, resource, namespace, name, metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}}); err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"kind":            resource.kind,
+		"name":            obj.GetName(),
+		"namespace":       obj.GetNamespace(),
+		"uid":             obj.GetUID(),
+		"resourceVersion": obj.GetResourceVersion(),
+		"dryRun":          true,
+	}
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		result["finalizers"] = finalizers
+	}
+
+	// 子对象以调用方的身份通过API查找，不使用informer缓存：模拟身份的客户端没有缓存，缓存中也可能有调用方无权查看的对象
+	find, failures := c.apiChildren(ctx, obj.GetNamespace())
+	if children := childTree(obj, 2, find); len(children) > 0 {
+		result["dependents"] = children
+	}
+	// 删除Namespace会删除其中的所有对象，它们通常没有ownerReferences
+	if resource.kind == "Namespace" && resource.gvr.Group == "" {
+		contents, contentFailures := c.listScope(ctx, obj.GetName())
+		failures = append(failures, contentFailures...)
+		counts := make(map[string]int)
+		for _, object := range contents {
+			counts[object.GetKind()]++
+		}
+		if len(counts) > 0 {
+			result["namespaceContents"] = counts
+		}
+	}
+	if len(failures) > 0 {
+		result["dependentsIncomplete"] = failures
+	}
+	return result, nil
 }
 
-func (c *Client) DeleteResource(ctx context.Context, kind, name, namespace string) error {
-	gvr, err := c.getCachedGVR(kind)
-	if err != nil {
-		return err
-	}
-	var deleteErr error
+// deleteObject namespace为空时删除集群级别的对象
+func (c *Client) deleteObject(ctx context.Context, resource *apiResource, namespace, name string, options metav1.DeleteOptions) error {
+	var err error
 	if namespace != "" {
-		deleteErr = c.dynamicClient.Resource(*gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		err = c.dynamicClient.Resource(resource.gvr).Namespace(namespace).Delete(ctx, name, options)
 	} else {
-		deleteErr = c.dynamicClient.Resource(*gvr).Delete(ctx, name, metav1.DeleteOptions{})
+		err = c.dynamicClient.Resource(resource.gvr).Delete(ctx, name, options)
 	}
-	if deleteErr != nil {
-		return fmt.Errorf("failed to delete resource: %w", deleteErr)
+	if err != nil {
+		return fmt.Errorf("failed to delete resource: %w", err)
 	}
 	return nil
 }
 
-// 使用dynamic client来获取资源的describe，传入kind,name,namespace参数
-// 返回资源的unstructured content通过map[string]interface{}返回
-// 其实和getresource一样
-func (c *Client) DescribeResource(ctx context.Context, kind, name, namespace string) (map[string]interface{}, error) {
-	// 首先尝试从本地缓存获取
-	if resource, found := c.getResourceFromCache(kind, namespace, name); found {
-		return resource, nil
-	}
-
-	// 缓存未命中，调用API Server
-	gvr, err := c.getCachedGVR(kind)
-	if err != nil {
-		return nil, err
+// listScope 列出namespace中（为空时是集群级别）所有可以list的资源的对象，跳过Event
+// 调用方无权list或list失败的资源返回在failures中，格式为 "kind: 错误"
+func (c *Client) listScope(ctx context.Context, namespace string) ([]*unstructured.Unstructured, []string) {
+	c.cacheLock.RLock()
+	resources := make([]*apiResource, 0, len(c.apiResources))
+	for _, resource := range c.apiResources {
+		if resource.namespaced != (namespace != "") || resource.kind == "Event" || !containsVerb(resource.verbs, "list") {
+			continue
+		}
+		resources = append(resources, resource)
 	}
-	var obj *unstructured.Unstructured
-	if namespace == "" {
-		obj, err = c.dynamicClient.Resource(*gvr).Get(ctx, name, metav1.GetOptions{})
-	} else {
-		obj, err = c.dynamicClient.Resource(*gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	c.cacheLock.RUnlock()
+	sort.Slice(resources, func(i, j int) bool { return resources[i].key < resources[j].key })
+
+	var objects []*unstructured.Unstructured
+	var failures []string
+	for _, resource := range resources {
+		var list *unstructured.UnstructuredList
+		var err error
+		if namespace != "" {
+			list, err = c.dynamicClient.Resource(resource.gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		} else {
+			list, err = c.dynamicClient.Resource(resource.gvr).List(ctx, metav1.ListOptions{})
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve resource: %w", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", resource.key, err))
+			continue
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
 		}
 	}
+	return objects, failures
+}
 
-	return obj.UnstructuredContent(), nil
+func containsVerb(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
 }
 
-// 使用clientset客户端获取日志，传入命名空间，pod名，容器名，以及行数参数
-// 返回日志字符串
+// 使用clientset客户端获取日志，传入命名空间，pod名和日志选项
+// 返回日志字符串，多个容器时每个容器的日志前有分隔行，日志总量受options.MaxBytes限制
 // 后面会加上从loki获取日志，支持更复杂的日志过滤策略
-func (c *Client) GetPodsLogs(ctx context.Context, namespace, containerName, podName string, LogstailLines int) (string, error) {
-	if LogstailLines > 300 {
-		LogstailLines = 300
+func (c *Client) GetPodsLogs(ctx context.Context, namespace, podName string, options PodLogOptions) (string, error) {
+	podLogOptions, err := options.toPodLogOptions()
+	if err != nil {
+		return "", err
+	}
+	filter, err := newLogFilter(options.Grep, options.ContextLines, options.Timestamps)
+	if err != nil {
+		return "", err
 	}
-	tailLines := int64(LogstailLines)
-	podLogOptions := &corev1.PodLogOptions{
-		TailLines: &tailLines,
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultLogBytes
 	}
 	//如果制定了container的name
-	if containerName != "" {
-		podLogOptions.Container = containerName
-		req := c.Clientset.CoreV1().Pods(namespace).GetLogs(podName, podLogOptions)
-		logs, err := req.Stream(ctx)
+	if options.Container != "" {
+		podLogOptions.Container = options.Container
+		logs, err := c.readPodLogs(ctx, namespace, podName, podLogOptions, filter, maxBytes)
 		if err != nil {
-			return "", fmt.Errorf("failed to get logs for container %s:%w", containerName, err)
+			return "", fmt.Errorf("failed to get logs for container %s:%w", options.Container, err)
 		}
-		defer logs.Close()
-		buf := new(bytes.Buffer)
-		if _, err := io.Copy(buf, logs); err != nil {
-			return "", fmt.Errorf("failed to copy logs to buffer:%w", err)
-		}
-		return buf.String(), nil
+		return logs, nil
 	}
 
 	//如果没有传递conmtainer name的话：
@@ -681,42 +697,26 @@ func (c *Client) GetPodsLogs(ctx context.Context, namespace, containerName, podN
 	}
 	//如果只有一个container的话
 	if len(pod.Spec.Containers) == 1 {
-		req := c.Clientset.CoreV1().Pods(namespace).GetLogs(podName, podLogOptions)
-		logs, err := req.Stream(ctx)
+		logs, err := c.readPodLogs(ctx, namespace, podName, podLogOptions, filter, maxBytes)
 		if err != nil {
 			return "", fmt.Errorf("failed to get logs: %w", err)
 		}
-		defer logs.Close()
-
-		buf := new(bytes.Buffer)
-		if _, err := io.Copy(buf, logs); err != nil {
-			return "", fmt.Errorf("failed to read logs: %w", err)
-		}
-		return buf.String(), nil
+		return logs, nil
 	}
-	//如果有多个容器的话：
+	//如果有多个容器的话，每个容器平分字节预算：
+	containerBytes := maxBytes / int64(len(pod.Spec.Containers))
 	var allLogs strings.Builder
 	for _, container := range pod.Spec.Containers {
 		containerLogOptions := podLogOptions.DeepCopy()
 		containerLogOptions.Container = container.Name
 
-		req := c.Clientset.CoreV1().Pods(namespace).GetLogs(podName, containerLogOptions)
-		logs, err := req.Stream(ctx)
+		logs, err := c.readPodLogs(ctx, namespace, podName, containerLogOptions, filter, containerBytes)
 		if err != nil {
 			allLogs.WriteString(fmt.Sprintf("\n--- Error getting logs for container %s: %v ---\n", container.Name, err))
 			continue
 		}
-
 		allLogs.WriteString(fmt.Sprintf("\n--- Logs for container %s ---\n", container.Name))
-		buf := new(bytes.Buffer)
-		_, err = io.Copy(buf, logs)
-		logs.Close()
-
-		if err != nil {
-			allLogs.WriteString(fmt.Sprintf("Error reading logs: %v\n", err))
-		} else {
-			allLogs.WriteString(buf.String())
-		}
+		allLogs.WriteString(logs)
 	}
 
 	return allLogs.String(), nil
@@ -939,8 +939,8 @@ func (c *Client) GetIngresses(ctx context.Context, host string) ([]map[string]in
 }
 
 // 滚动更新pod实现，可以更新 Deployment、DomonSet以及Statefulset ...
-// 通过给它打一个annotation加上当前的时间戳来实现滚动更新
-func (c *Client) RolloutRestart(ctx context.Context, kind, name, namespace string) (map[string]interface{}, error) {
+// 通过给它打一个annotation加上当前的时间戳来实现滚动更新，dryRun为true时只返回修改后的对象，不会真正重启
+func (c *Client) RolloutRestart(ctx context.Context, kind, name, namespace string, dryRun bool) (map[string]interface{}, error) {
 	gvr, err := c.getCachedGVR(kind)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get gvr for kind %s :%w", kind, err)
@@ -950,7 +950,11 @@ func (c *Client) RolloutRestart(ctx context.Context, kind, name, namespace strin
 		`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`,
 		time.Now().Format(time.RFC3339),
 	))
-	result, err := resource.Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	options := metav1.PatchOptions{}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	result, err := resource.Patch(ctx, name, types.StrategicMergePatchType, patch, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to rollout %s %s %s :%w", kind, namespace, name, err)
 	}
@@ -0,0 +1,648 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// 沿ownerReferences向上查找的最大层数，防止异常数据导致死循环
+const maxOwnerDepth = 10
+
+// 每一层最多返回的子对象数量
+const maxChildren = 50
+
+// DescribeResource 和kubectl describe类似，返回对象的概要信息而不是完整的对象：
+// spec和status的摘要、带时间的conditions、ownerReferences链、子对象（例如Deployment的ReplicaSet和Pod），
+// 以及involvedObject是这个对象的Events（按时间排序）
+func (c *Client) DescribeResource(ctx context.Context, kind, name, namespace string) (map[string]interface{}, error) {
+	resource, err := c.resolveAPIResource(kind)
+	if err != nil {
+		return nil, err
+	}
+	if !resource.namespaced {
+		namespace = ""
+	}
+	obj, err := c.getObject(ctx, resource, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := obj.GetAnnotations()
+	delete(annotations, lastAppliedAnnotation)
+	result := map[string]interface{}{
+		"kind":        resource.kind,
+		"apiVersion":  obj.GetAPIVersion(),
+		"name":        obj.GetName(),
+		"namespace":   obj.GetNamespace(),
+		"uid":         obj.GetUID(),
+		"created":     obj.GetCreationTimestamp().UTC().Format(time.RFC3339),
+		"age":         humanDuration(time.Since(obj.GetCreationTimestamp().Time)),
+		"labels":      obj.GetLabels(),
+		"annotations": annotations,
+		"summary":     describeSummary(resource.kind, obj),
+	}
+	if deletion := obj.GetDeletionTimestamp(); deletion != nil {
+		result["deletionTimestamp"] = deletion.UTC().Format(time.RFC3339)
+		result["finalizers"] = obj.GetFinalizers()
+	}
+	if conditions := describeConditions(obj); len(conditions) > 0 {
+		result["conditions"] = conditions
+	}
+	if owners := c.ownerChain(ctx, obj); len(owners) > 0 {
+		result["owners"] = owners
+	}
+	children, failures := c.describeChildren(ctx, obj, 2)
+	if len(children) > 0 {
+		result["children"] = children
+	}
+	if len(failures) > 0 {
+		result["childrenIncomplete"] = failures
+	}
+	events, err := c.objectEvents(ctx, resource.kind, obj)
+	if err != nil {
+		result["eventsError"] = err.Error()
+	} else {
+		result["events"] = events
+	}
+	// 摘要中有typed的结构体，转换为普通的map，这样fields和jsonPath投影可以按json字段名工作
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize description: %w", err)
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to serialize description: %w", err)
+	}
+	return normalized, nil
+}
+
+// getObject 先从缓存获取对象，缓存未命中时调用API Server
+func (c *Client) getObject(ctx context.Context, resource *apiResource, namespace, name string) (*unstructured.Unstructured, error) {
+	c.informerLock.RLock()
+	store, exists := c.resourceCaches[resource.key]
+	c.informerLock.RUnlock()
+	if exists {
+		if item, found, _ := store.GetByKey(c.getResourceCacheKey(resource.key, namespace, name)); found {
+			if obj, ok := item.(*unstructured.Unstructured); ok {
+				return obj.DeepCopy(), nil
+			}
+		}
+	}
+	var obj *unstructured.Unstructured
+	var err error
+	if namespace != "" {
+		obj, err = c.dynamicClient.Resource(resource.gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		obj, err = c.dynamicClient.Resource(resource.gvr).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve resource: %w", err)
+	}
+	return obj, nil
+}
+
+// ownerChain 沿controller（没有controller时取第一个owner）向上查找，直到没有owner的顶层对象
+func (c *Client) ownerChain(ctx context.Context, obj *unstructured.Unstructured) []map[string]interface{} {
+	chain := []map[string]interface{}{}
+	current := obj
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		refs := current.GetOwnerReferences()
+		if len(refs) == 0 {
+			break
+		}
+		ref := refs[0]
+		for _, r := range refs {
+			if r.Controller != nil && *r.Controller {
+				ref = r
+				break
+			}
+		}
+		entry := map[string]interface{}{"kind": ref.Kind, "name": ref.Name}
+		chain = append(chain, entry)
+		resource, err := c.resolveGVK(ref.APIVersion, ref.Kind)
+		if err != nil {
+			entry["error"] = err.Error()
+			break
+		}
+		namespace := ""
+		if resource.namespaced {
+			namespace = obj.GetNamespace()
+		}
+		owner, err := c.getObject(ctx, resource, namespace, ref.Name)
+		if err != nil {
+			entry["error"] = err.Error()
+			break
+		}
+		// 同名的owner被删除重建后uid会不同
+		if owner.GetUID() != ref.UID {
+			entry["error"] = "owner no longer exists"
+			break
+		}
+		current = owner
+	}
+	return chain
+}
+
+// describeChildren 查找ownerReferences指向obj的对象，depth控制向下查找的层数
+// 服务器自己的客户端使用informer缓存；模拟身份的客户端没有缓存，以调用方的身份通过API查找，无权list的资源类型返回在failures中
+func (c *Client) describeChildren(ctx context.Context, obj *unstructured.Unstructured, depth int) ([]map[string]interface{}, []string) {
+	if c.base == nil {
+		return childTree(obj, depth, c.cachedChildren), nil
+	}
+	find, failures := c.apiChildren(ctx, obj.GetNamespace())
+	return childTree(obj, depth, find), failures
+}
+
+// apiChildren 以调用方的身份列出namespace中（为空时是集群级别）的对象，返回按ownerReferences查找子对象的函数
+func (c *Client) apiChildren(ctx context.Context, namespace string) (func(*unstructured.Unstructured) []*unstructured.Unstructured, []string) {
+	objects, failures := c.listScope(ctx, namespace)
+	owned := make(map[types.UID][]*unstructured.Unstructured)
+	for _, object := range objects {
+		for _, ref := range object.GetOwnerReferences() {
+			owned[ref.UID] = append(owned[ref.UID], object)
+		}
+	}
+	return func(owner *unstructured.Unstructured) []*unstructured.Unstructured {
+		return owned[owner.GetUID()]
+	}, failures
+}
+
+// cachedChildren 在informer缓存中查找ownerReferences直接指向obj的对象
+func (c *Client) cachedChildren(obj *unstructured.Unstructured) []*unstructured.Unstructured {
+	uid := obj.GetUID()
+	namespace := obj.GetNamespace()
+
+	c.informerLock.RLock()
+	stores := make(map[string]cache.Store, len(c.resourceCaches))
+	for key, store := range c.resourceCaches {
+		stores[key] = store
+	}
+	c.informerLock.RUnlock()
+
+	var children []*unstructured.Unstructured
+	for key, store := range stores {
+		// Event的ownerReferences一般为空，跳过可以少遍历大量对象
+		if key == "Event" || strings.HasPrefix(key, "Event.") {
+			continue
+		}
+		if c.isNamespaced(key) != (namespace != "") {
+			continue
+		}
+		var items []interface{}
+		if indexer, ok := store.(cache.Indexer); ok && namespace != "" {
+			items, _ = indexer.ByIndex(cache.NamespaceIndex, namespace)
+		} else {
+			items = store.List()
+		}
+		for _, item := range items {
+			child, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			for _, ref := range child.GetOwnerReferences() {
+				if ref.UID == uid {
+					children = append(children, child)
+					break
+				}
+			}
+		}
+	}
+	return children
+}
+
+// childTree 用find查找每一层的子对象，按kind和名称排序后向下查找depth层
+func childTree(obj *unstructured.Unstructured, depth int, find func(*unstructured.Unstructured) []*unstructured.Unstructured) []map[string]interface{} {
+	if depth <= 0 {
+		return nil
+	}
+	children := find(obj)
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].GetKind() != children[j].GetKind() {
+			return children[i].GetKind() < children[j].GetKind()
+		}
+		return children[i].GetName() < children[j].GetName()
+	})
+
+	result := []map[string]interface{}{}
+	for i, child := range children {
+		if i >= maxChildren {
+			result = append(result, map[string]interface{}{"truncated": len(children) - maxChildren})
+			break
+		}
+		entry := map[string]interface{}{
+			"kind":   child.GetKind(),
+			"name":   child.GetName(),
+			"status": childStatus(child),
+		}
+		if grandChildren := childTree(child, depth-1, find); len(grandChildren) > 0 {
+			entry["children"] = grandChildren
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// childStatus 返回子对象的简短状态
+func childStatus(obj *unstructured.Unstructured) string {
+	switch obj.GetKind() {
+	case "Pod":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+		ready, restarts := 0, int64(0)
+		reason := ""
+		for _, s := range statuses {
+			status, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if isReady, _, _ := unstructured.NestedBool(status, "ready"); isReady {
+				ready++
+			}
+			count, _, _ := unstructured.NestedInt64(status, "restartCount")
+			restarts += count
+			if waiting, _, _ := unstructured.NestedString(status, "state", "waiting", "reason"); waiting != "" {
+				reason = waiting
+			}
+		}
+		if reason != "" {
+			phase = reason
+		}
+		return fmt.Sprintf("%s, %d/%d ready, %d restarts", phase, ready, len(statuses), restarts)
+	case "ReplicaSet", "StatefulSet", "ReplicationController":
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		return fmt.Sprintf("%d/%d ready", ready, desired)
+	case "Job":
+		succeeded, _, _ := unstructured.NestedInt64(obj.Object, "status", "succeeded")
+		failed, _, _ := unstructured.NestedInt64(obj.Object, "status", "failed")
+		active, _, _ := unstructured.NestedInt64(obj.Object, "status", "active")
+		return fmt.Sprintf("%d active, %d succeeded, %d failed", active, succeeded, failed)
+	}
+	return ""
+}
+
+// describeConditions 返回status.conditions，只保留排查问题需要的字段
+func describeConditions(obj *unstructured.Unstructured) []map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	result := make([]map[string]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entry := map[string]interface{}{}
+		for _, field := range []string{"type", "status", "reason", "message", "lastTransitionTime", "lastUpdateTime", "lastProbeTime", "lastHeartbeatTime"} {
+			if value, ok := condition[field]; ok && value != nil && value != "" {
+				entry[field] = value
+			}
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// describeSummary 按kind返回spec和status的摘要，未知的kind返回spec和去掉conditions的status
+func describeSummary(kind string, obj *unstructured.Unstructured) map[string]interface{} {
+	content := obj.Object
+	switch kind {
+	case "Pod":
+		return describePod(obj)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController":
+		summary := map[string]interface{}{}
+		if replicas, found, _ := unstructured.NestedInt64(content, "spec", "replicas"); found {
+			summary["desired"] = replicas
+		}
+		for _, field := range []string{"replicas", "updatedReplicas", "readyReplicas", "availableReplicas", "unavailableReplicas",
+			"currentNumberScheduled", "desiredNumberScheduled", "numberReady", "numberAvailable", "updatedNumberScheduled",
+			"observedGeneration", "currentRevision", "updateRevision"} {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, "status", field); found {
+				summary[field] = value
+			}
+		}
+		summary["generation"] = obj.GetGeneration()
+		if selector, found, _ := unstructured.NestedFieldNoCopy(content, "spec", "selector"); found {
+			summary["selector"] = selector
+		}
+		for _, field := range []string{"strategy", "updateStrategy", "podManagementPolicy", "serviceName", "paused"} {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, "spec", field); found {
+				summary[field] = value
+			}
+		}
+		summary["containers"] = templateContainers(content, "spec", "template", "spec")
+		return summary
+	case "Service":
+		summary := map[string]interface{}{}
+		for _, field := range []string{"type", "clusterIP", "externalIPs", "externalName", "ports", "selector", "sessionAffinity", "loadBalancerIP"} {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, "spec", field); found {
+				summary[field] = value
+			}
+		}
+		if ingress, found, _ := unstructured.NestedFieldNoCopy(content, "status", "loadBalancer", "ingress"); found {
+			summary["loadBalancerIngress"] = ingress
+		}
+		return summary
+	case "Node":
+		summary := map[string]interface{}{}
+		unschedulable, _, _ := unstructured.NestedBool(content, "spec", "unschedulable")
+		summary["unschedulable"] = unschedulable
+		for _, field := range []string{"taints", "podCIDR", "providerID"} {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, "spec", field); found {
+				summary[field] = value
+			}
+		}
+		for _, field := range []string{"addresses", "capacity", "allocatable", "nodeInfo"} {
+			if value, found, _ := unstructured.NestedFieldNoCopy(content, "status", field); found {
+				summary[field] = value
+			}
+		}
+		return summary
+	case "Job", "CronJob":
+		summary := map[string]interface{}{}
+		if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+			for key, value := range spec {
+				if key != "template" && key != "jobTemplate" {
+					summary[key] = value
+				}
+			}
+		}
+		if status, ok := obj.Object["status"].(map[string]interface{}); ok {
+			for key, value := range status {
+				if key != "conditions" {
+					summary[key] = value
+				}
+			}
+		}
+		if kind == "Job" {
+			summary["containers"] = templateContainers(content, "spec", "template", "spec")
+		} else {
+			summary["containers"] = templateContainers(content, "spec", "jobTemplate", "spec", "template", "spec")
+		}
+		return summary
+	}
+	summary := map[string]interface{}{}
+	if spec, ok := content["spec"]; ok {
+		summary["spec"] = spec
+	}
+	if status, ok := content["status"].(map[string]interface{}); ok {
+		rest := map[string]interface{}{}
+		for key, value := range status {
+			if key != "conditions" {
+				rest[key] = value
+			}
+		}
+		if len(rest) > 0 {
+			summary["status"] = rest
+		}
+	}
+	// ConfigMap、Secret这样没有spec的对象
+	if len(summary) == 0 {
+		for key, value := range content {
+			if key == "data" || key == "binaryData" || key == "stringData" {
+				keys := make([]string, 0)
+				if m, ok := value.(map[string]interface{}); ok {
+					for k := range m {
+						keys = append(keys, k)
+					}
+				}
+				sort.Strings(keys)
+				summary[key+"Keys"] = keys
+			} else if key != "metadata" && key != "apiVersion" && key != "kind" {
+				summary[key] = value
+			}
+		}
+	}
+	return summary
+}
+
+// templateContainers 返回pod template中容器的名称、镜像和资源配置
+func templateContainers(content map[string]interface{}, podSpecPath ...string) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(content, append(podSpecPath, field)...)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			entry := map[string]interface{}{
+				"name":  container["name"],
+				"image": container["image"],
+			}
+			if field == "initContainers" {
+				entry["init"] = true
+			}
+			if resources, ok := container["resources"]; ok {
+				entry["resources"] = resources
+			}
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// describePod 返回pod的状态和每个容器的状态
+func describePod(obj *unstructured.Unstructured) map[string]interface{} {
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	summary := map[string]interface{}{
+		"phase":          pod.Status.Phase,
+		"node":           pod.Spec.NodeName,
+		"podIP":          pod.Status.PodIP,
+		"qosClass":       pod.Status.QOSClass,
+		"serviceAccount": pod.Spec.ServiceAccountName,
+		"restartPolicy":  pod.Spec.RestartPolicy,
+	}
+	if pod.Status.StartTime != nil {
+		summary["startTime"] = pod.Status.StartTime.UTC().Format(time.RFC3339)
+	}
+	if pod.Status.Reason != "" {
+		summary["reason"] = pod.Status.Reason
+		summary["message"] = pod.Status.Message
+	}
+	if len(pod.Spec.NodeSelector) > 0 {
+		summary["nodeSelector"] = pod.Spec.NodeSelector
+	}
+	if len(pod.Spec.Tolerations) > 0 {
+		summary["tolerations"] = pod.Spec.Tolerations
+	}
+
+	specs := map[string]corev1.Container{}
+	for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		specs[container.Name] = container
+	}
+	containers := []map[string]interface{}{}
+	addStatuses := func(statuses []corev1.ContainerStatus, init bool) {
+		for _, status := range statuses {
+			entry := map[string]interface{}{
+				"name":         status.Name,
+				"image":        status.Image,
+				"ready":        status.Ready,
+				"restartCount": status.RestartCount,
+				"state":        containerStateString(status.State),
+			}
+			if init {
+				entry["init"] = true
+			}
+			if status.LastTerminationState.Terminated != nil {
+				entry["lastState"] = containerStateString(status.LastTerminationState)
+			}
+			if spec, ok := specs[status.Name]; ok {
+				if len(spec.Resources.Requests) > 0 || len(spec.Resources.Limits) > 0 {
+					entry["resources"] = spec.Resources
+				}
+				if spec.LivenessProbe != nil {
+					entry["livenessProbe"] = probeString(spec.LivenessProbe)
+				}
+				if spec.ReadinessProbe != nil {
+					entry["readinessProbe"] = probeString(spec.ReadinessProbe)
+				}
+			}
+			containers = append(containers, entry)
+		}
+	}
+	addStatuses(pod.Status.InitContainerStatuses, true)
+	addStatuses(pod.Status.ContainerStatuses, false)
+	// 还没有被调度时没有容器状态，只返回spec中的容器
+	if len(containers) == 0 {
+		summary["containers"] = templateContainers(obj.Object, "spec")
+	} else {
+		summary["containers"] = containers
+	}
+	return summary
+}
+
+// containerStateString 把容器状态转换为类似 "Waiting: CrashLoopBackOff" 的描述
+func containerStateString(state corev1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running since " + state.Running.StartedAt.UTC().Format(time.RFC3339)
+	case state.Waiting != nil:
+		if state.Waiting.Message != "" {
+			return fmt.Sprintf("Waiting: %s (%s)", state.Waiting.Reason, state.Waiting.Message)
+		}
+		return "Waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		t := state.Terminated
+		text := fmt.Sprintf("Terminated: %s (exit code %d) at %s", t.Reason, t.ExitCode, t.FinishedAt.UTC().Format(time.RFC3339))
+		if t.Message != "" {
+			text += ": " + t.Message
+		}
+		return text
+	}
+	return "Unknown"
+}
+
+// probeString 把探针转换为类似kubectl describe的一行描述
+func probeString(probe *corev1.Probe) string {
+	var action string
+	switch {
+	case probe.HTTPGet != nil:
+		action = fmt.Sprintf("http-get %s:%s%s", probe.HTTPGet.Scheme, probe.HTTPGet.Port.String(), probe.HTTPGet.Path)
+	case probe.TCPSocket != nil:
+		action = "tcp-socket :" + probe.TCPSocket.Port.String()
+	case probe.Exec != nil:
+		action = "exec " + strings.Join(probe.Exec.Command, " ")
+	case probe.GRPC != nil:
+		action = fmt.Sprintf("grpc :%d", probe.GRPC.Port)
+	}
+	return fmt.Sprintf("%s delay=%ds timeout=%ds period=%ds #success=%d #failure=%d", action,
+		probe.InitialDelaySeconds, probe.TimeoutSeconds, probe.PeriodSeconds, probe.SuccessThreshold, probe.FailureThreshold)
+}
+
+// objectEvents 返回involvedObject是obj的Events，按时间从旧到新排序
+// 优先从informer缓存中获取，缓存不可用时通过字段选择器请求API Server
+func (c *Client) objectEvents(ctx context.Context, kind string, obj *unstructured.Unstructured) ([]map[string]interface{}, error) {
+	selector := fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": obj.GetName(),
+	}
+	if obj.GetNamespace() != "" {
+		selector["involvedObject.namespace"] = obj.GetNamespace()
+	}
+	var events []corev1.Event
+	if cached, found := c.listResourcesFromCache("Event", obj.GetNamespace(), "", selector.AsSelector().String()); found {
+		for _, item := range cached {
+			event := corev1.Event{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &event); err != nil {
+				continue
+			}
+			events = append(events, event)
+		}
+	} else {
+		list, err := c.Clientset.CoreV1().Events(obj.GetNamespace()).List(ctx, metav1.ListOptions{FieldSelector: selector.AsSelector().String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve events:%w", err)
+		}
+		events = list.Items
+	}
+	return formatEvents(events, obj.GetUID()), nil
+}
+
+// formatEvents 过滤掉属于同名旧对象的事件和噪声事件，并按时间排序
+func formatEvents(events []corev1.Event, uid types.UID) []map[string]interface{} {
+	type timedEvent struct {
+		ts    string
+		time  time.Time
+		event corev1.Event
+	}
+	var timed []timedEvent
+	for _, event := range events {
+		if uid != "" && event.InvolvedObject.UID != "" && event.InvolvedObject.UID != uid {
+			continue
+		}
+		ts, _ := normalizeEventTimestamp(&event)
+		if isNoiseEvent(&event, ts) {
+			continue
+		}
+		// RFC3339Nano会去掉小数部分末尾的0，按字符串排序不等于按时间排序；没有时间的事件排在最前面
+		parsed, _ := time.Parse(time.RFC3339Nano, ts)
+		timed = append(timed, timedEvent{ts: ts, time: parsed, event: event})
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].time.Before(timed[j].time)
+	})
+	result := make([]map[string]interface{}, 0, len(timed))
+	for _, t := range timed {
+		source := t.event.Source.Component
+		if source == "" {
+			source = t.event.ReportingController
+		}
+		result = append(result, map[string]interface{}{
+			"time":    t.ts,
+			"type":    t.event.Type,
+			"reason":  t.event.Reason,
+			"message": t.event.Message,
+			"source":  source,
+			"count":   t.event.Count,
+		})
+	}
+	return result
+}
+
+// humanDuration 把时间间隔转换为kubectl风格的简短描述，例如 3d4h、5m
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
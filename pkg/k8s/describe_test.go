@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFormatEventsSortsByTime(t *testing.T) {
+	base := time.Date(2024, 5, 1, 8, 30, 1, 0, time.UTC)
+	event := func(reason string, at time.Time) corev1.Event {
+		return corev1.Event{Reason: reason, Message: reason, LastTimestamp: metav1.NewTime(at)}
+	}
+	// "08:30:01.5Z" 按字符串排在 "08:30:01Z" 前面
+	events := []corev1.Event{
+		event("third", base.Add(2*time.Second)),
+		event("second", base.Add(500*time.Millisecond)),
+		event("first", base),
+	}
+	got := formatEvents(events, "")
+	var reasons []string
+	for _, e := range got {
+		reasons = append(reasons, e["reason"].(string))
+	}
+	if len(reasons) != 3 || reasons[0] != "first" || reasons[1] != "second" || reasons[2] != "third" {
+		t.Errorf("formatEvents() order = %v, want [first second third]", reasons)
+	}
+}
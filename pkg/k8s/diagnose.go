@@ -0,0 +1,845 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// 诊断时读取的日志行数和字节数
+const (
+	diagnoseLogLines = 50
+	diagnoseLogBytes = 16 * 1024
+)
+
+// 每个问题最多保留的事件消息数量
+const maxFindingEvents = 5
+
+// 诊断出的问题分类
+const (
+	FindingCrashLoopBackOff   = "CrashLoopBackOff"
+	FindingImagePullBackOff   = "ImagePullBackOff"
+	FindingOOMKilled          = "OOMKilled"
+	FindingUnschedulable      = "Unschedulable"
+	FindingProbeFailure       = "ProbeFailure"
+	FindingInitContainerStuck = "InitContainerStuck"
+	FindingContainerConfig    = "ContainerConfigError"
+	FindingContainerFailed    = "ContainerFailed"
+	FindingVolumeMount        = "VolumeMountFailure"
+	FindingNodeProblem        = "NodeProblem"
+	FindingEvicted            = "Evicted"
+	FindingStuckTerminating   = "StuckTerminating"
+)
+
+// PodFinding 是诊断出的一个问题，Evidence中是得出结论所依据的状态、事件和日志
+type PodFinding struct {
+	Category     string                 `json:"category"`
+	Container    string                 `json:"container,omitempty"`
+	Summary      string                 `json:"summary"`
+	LikelyCauses []string               `json:"likelyCauses,omitempty"`
+	Evidence     map[string]interface{} `json:"evidence,omitempty"`
+}
+
+// PodDiagnosis 是DiagnosePod的结果，Findings为空并且Healthy为true表示没有发现问题
+type PodDiagnosis struct {
+	Pod            string                   `json:"pod"`
+	Namespace      string                   `json:"namespace"`
+	Phase          string                   `json:"phase"`
+	Node           string                   `json:"node,omitempty"`
+	Healthy        bool                     `json:"healthy"`
+	Findings       []PodFinding             `json:"findings"`
+	Containers     interface{}              `json:"containers,omitempty"`
+	NodeConditions []map[string]interface{} `json:"nodeConditions,omitempty"`
+	Events         []map[string]interface{} `json:"events,omitempty"`
+	EventsError    string                   `json:"eventsError,omitempty"`
+}
+
+// podDiagnoser 保存一次诊断中用到的数据
+type podDiagnoser struct {
+	client   *Client
+	pod      *corev1.Pod
+	events   []map[string]interface{}
+	node     *corev1.Node
+	findings []PodFinding
+}
+
+// DiagnosePod 检查Pod的状态、事件、上一次退出的容器日志和所在节点，给出问题分类和可能的原因
+// 一次调用代替 getResource、getEvents、getPodsLogs、describeResource(Node) 等多次调用
+func (c *Client) DiagnosePod(ctx context.Context, namespace, name string) (*PodDiagnosis, error) {
+	obj, err := c.GetResource(ctx, "Pod", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, pod); err != nil {
+		return nil, fmt.Errorf("failed to convert pod: %w", err)
+	}
+
+	diagnosis := &PodDiagnosis{
+		Pod:        pod.Name,
+		Namespace:  pod.Namespace,
+		Phase:      string(pod.Status.Phase),
+		Node:       pod.Spec.NodeName,
+		Containers: describePod(u)["containers"],
+	}
+	d := &podDiagnoser{client: c, pod: pod}
+	if events, err := c.objectEvents(ctx, "Pod", u); err != nil {
+		diagnosis.EventsError = err.Error()
+	} else {
+		d.events = events
+		diagnosis.Events = events
+	}
+	if pod.Spec.NodeName != "" {
+		if nodeObj, err := c.GetResource(ctx, "Node", pod.Spec.NodeName, ""); err == nil {
+			node := &corev1.Node{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(nodeObj, node); err == nil {
+				d.node = node
+				diagnosis.NodeConditions = nodeConditions(node)
+			}
+		}
+	}
+
+	d.checkTerminating()
+	d.checkEvicted()
+	d.checkScheduling(ctx)
+	d.checkInitContainers(ctx)
+	d.checkContainers(ctx)
+	d.checkVolumes()
+	d.checkProbes()
+	d.checkNode()
+
+	diagnosis.Findings = d.findings
+	if diagnosis.Findings == nil {
+		diagnosis.Findings = []PodFinding{}
+	}
+	diagnosis.Healthy = len(d.findings) == 0 && podLooksHealthy(pod)
+	return diagnosis, nil
+}
+
+func (d *podDiagnoser) add(finding PodFinding) {
+	d.findings = append(d.findings, finding)
+}
+
+// eventMessages 返回reason匹配的事件消息（去重，最新的在后面）
+func (d *podDiagnoser) eventMessages(reasons ...string) []string {
+	seen := map[string]bool{}
+	var messages []string
+	for _, event := range d.events {
+		reason, _ := event["reason"].(string)
+		message, _ := event["message"].(string)
+		for _, r := range reasons {
+			if reason == r && !seen[message] {
+				seen[message] = true
+				messages = append(messages, message)
+			}
+		}
+	}
+	if len(messages) > maxFindingEvents {
+		messages = messages[len(messages)-maxFindingEvents:]
+	}
+	return messages
+}
+
+// containerLogs 读取容器最后几行日志，previous为true时读取上一次退出的容器
+func (d *podDiagnoser) containerLogs(ctx context.Context, container string, previous bool) (string, error) {
+	tailLines := int64(diagnoseLogLines)
+	return d.client.readPodLogs(ctx, d.pod.Namespace, d.pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+		TailLines: &tailLines,
+	}, nil, diagnoseLogBytes)
+}
+
+// addLogs 把日志或读取日志的错误加入evidence
+func (d *podDiagnoser) addLogs(ctx context.Context, evidence map[string]interface{}, container string, previous bool) string {
+	key := "logs"
+	if previous {
+		key = "previousLogs"
+	}
+	logs, err := d.containerLogs(ctx, container, previous)
+	if err != nil {
+		evidence[key+"Error"] = err.Error()
+		return ""
+	}
+	evidence[key] = logs
+	return logs
+}
+
+func (d *podDiagnoser) containerSpec(name string) *corev1.Container {
+	for i := range d.pod.Spec.InitContainers {
+		if d.pod.Spec.InitContainers[i].Name == name {
+			return &d.pod.Spec.InitContainers[i]
+		}
+	}
+	for i := range d.pod.Spec.Containers {
+		if d.pod.Spec.Containers[i].Name == name {
+			return &d.pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func (d *podDiagnoser) checkTerminating() {
+	deletion := d.pod.DeletionTimestamp
+	// DeletionTimestamp 已经包含了优雅退出的时间，过了这个时间还存在就是卡住了
+	if deletion == nil || time.Since(deletion.Time) < 0 {
+		return
+	}
+	evidence := map[string]interface{}{
+		"deletionTimestamp": deletion.UTC().Format(time.RFC3339),
+		"terminatingFor":    humanDuration(time.Since(deletion.Time)),
+	}
+	var causes []string
+	if len(d.pod.Finalizers) > 0 {
+		evidence["finalizers"] = d.pod.Finalizers
+		causes = append(causes, fmt.Sprintf("the finalizers %s have not been removed, the controller that owns them may be down", strings.Join(d.pod.Finalizers, ", ")))
+	}
+	if d.node != nil && !nodeReady(d.node) {
+		causes = append(causes, fmt.Sprintf("node %s is not ready, so the kubelet cannot confirm the containers stopped", d.node.Name))
+	}
+	causes = append(causes, "a container ignores SIGTERM or the kubelet cannot stop it; as a last resort delete the pod with a grace period of 0")
+	d.add(PodFinding{
+		Category:     FindingStuckTerminating,
+		Summary:      "the pod was deleted but has not terminated",
+		LikelyCauses: causes,
+		Evidence:     evidence,
+	})
+}
+
+func (d *podDiagnoser) checkEvicted() {
+	if d.pod.Status.Reason != "Evicted" {
+		return
+	}
+	causes := []string{"the node ran low on a resource (memory, disk or PIDs) and the kubelet evicted the pod"}
+	if strings.Contains(d.pod.Status.Message, "ephemeral-storage") {
+		causes = []string{"the pod used more ephemeral storage (logs, emptyDir, writable layer) than the node could spare, set an ephemeral-storage limit or clean up the files it writes"}
+	} else if strings.Contains(d.pod.Status.Message, "memory") {
+		causes = []string{"the node was under memory pressure, pods using more memory than they request are evicted first, raise the memory request"}
+	}
+	d.add(PodFinding{
+		Category:     FindingEvicted,
+		Summary:      "the pod was evicted by the kubelet",
+		LikelyCauses: causes,
+		Evidence:     map[string]interface{}{"message": d.pod.Status.Message},
+	})
+}
+
+func (d *podDiagnoser) checkScheduling(ctx context.Context) {
+	if d.pod.Spec.NodeName != "" {
+		return
+	}
+	var message string
+	unschedulable := false
+	for _, condition := range d.pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			message = condition.Message
+			unschedulable = condition.Reason == corev1.PodReasonUnschedulable
+		}
+	}
+	events := d.eventMessages("FailedScheduling")
+	if !unschedulable && len(events) == 0 {
+		if d.pod.Status.Phase == corev1.PodPending && d.pod.Spec.SchedulerName != "" && d.pod.Spec.SchedulerName != corev1.DefaultSchedulerName &&
+			time.Since(d.pod.CreationTimestamp.Time) > time.Minute {
+			d.add(PodFinding{
+				Category:     FindingUnschedulable,
+				Summary:      "the pod has not been scheduled and no scheduler reported on it",
+				LikelyCauses: []string{fmt.Sprintf("the pod uses schedulerName %s, that scheduler may not be running", d.pod.Spec.SchedulerName)},
+				Evidence:     map[string]interface{}{"schedulerName": d.pod.Spec.SchedulerName},
+			})
+		}
+		return
+	}
+	if message == "" && len(events) > 0 {
+		message = events[len(events)-1]
+	}
+
+	evidence := map[string]interface{}{"message": message}
+	if len(events) > 0 {
+		evidence["schedulerEvents"] = events
+	}
+	if requests := podRequests(d.pod); len(requests) > 0 {
+		evidence["requests"] = requests
+	}
+	if len(d.pod.Spec.NodeSelector) > 0 {
+		evidence["nodeSelector"] = d.pod.Spec.NodeSelector
+	}
+	if d.pod.Spec.Affinity != nil {
+		evidence["affinity"] = d.pod.Spec.Affinity
+	}
+	if len(d.pod.Spec.Tolerations) > 0 {
+		evidence["tolerations"] = d.pod.Spec.Tolerations
+	}
+	if nodes, err := d.client.nodesOverview(ctx); err == nil {
+		evidence["nodes"] = nodes
+	}
+	d.add(PodFinding{
+		Category:     FindingUnschedulable,
+		Summary:      "the scheduler cannot find a node for the pod",
+		LikelyCauses: schedulingCauses(strings.Join(append([]string{message}, events...), "\n")),
+		Evidence:     evidence,
+	})
+}
+
+// schedulingCauses 根据调度器的消息（例如 0/3 nodes are available: 3 Insufficient cpu.）给出原因
+func schedulingCauses(message string) []string {
+	lower := strings.ToLower(message)
+	var causes []string
+	for _, resource := range []string{"cpu", "memory", "ephemeral-storage", "nvidia.com/gpu"} {
+		if strings.Contains(lower, "insufficient "+resource) {
+			causes = append(causes, fmt.Sprintf("no node has enough allocatable %s left for the pod's requests, lower the requests or add capacity", resource))
+		}
+	}
+	checks := []struct {
+		patterns []string
+		cause    string
+	}{
+		{[]string{"untolerated taint", "had taint"}, "the nodes have taints the pod does not tolerate"},
+		{[]string{"node affinity/selector", "didn't match node selector", "node(s) didn't match pod's node affinity"}, "the nodeSelector or node affinity does not match any node's labels"},
+		{[]string{"anti-affinity", "didn't match pod affinity"}, "pod affinity or anti-affinity rules cannot be satisfied with the current pods"},
+		{[]string{"unbound immediate persistentvolumeclaims", "persistentvolumeclaim", "volume node affinity conflict"}, "a PersistentVolumeClaim is missing, not bound, or its volume is only reachable from other nodes (zone)"},
+		{[]string{"too many pods"}, "the nodes reached their maximum number of pods"},
+		{[]string{"free ports", "free port"}, "the requested hostPort is already used on every candidate node"},
+		{[]string{"were unschedulable", "unschedulable:"}, "the candidate nodes are cordoned"},
+		{[]string{"not-ready", "not ready", "unreachable"}, "the candidate nodes are not ready"},
+		{[]string{"topology spread"}, "topologySpreadConstraints cannot be satisfied"},
+	}
+	for _, check := range checks {
+		for _, pattern := range check.patterns {
+			if strings.Contains(lower, pattern) {
+				causes = append(causes, check.cause)
+				break
+			}
+		}
+	}
+	if len(causes) == 0 {
+		causes = append(causes, "see the scheduler message in the evidence")
+	}
+	return causes
+}
+
+// podRequests 汇总所有容器的资源请求
+func podRequests(pod *corev1.Pod) map[string]string {
+	total := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+	}
+	result := make(map[string]string, len(total))
+	for name, quantity := range total {
+		result[string(name)] = quantity.String()
+	}
+	return result
+}
+
+func (d *podDiagnoser) checkInitContainers(ctx context.Context) {
+	for _, status := range d.pod.Status.InitContainerStatuses {
+		spec := d.containerSpec(status.Name)
+		// restartPolicy为Always的init容器是sidecar，会一直运行
+		if spec != nil && spec.RestartPolicy != nil && *spec.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			d.checkContainer(ctx, status, true)
+			continue
+		}
+		if status.State.Terminated != nil && status.State.Terminated.ExitCode == 0 {
+			continue
+		}
+		if d.checkContainer(ctx, status, true) {
+			return
+		}
+		if status.State.Running != nil && d.pod.Status.Phase == corev1.PodPending {
+			evidence := map[string]interface{}{
+				"state":        containerStateString(status.State),
+				"runningFor":   humanDuration(time.Since(status.State.Running.StartedAt.Time)),
+				"restartCount": status.RestartCount,
+			}
+			if spec != nil {
+				evidence["command"] = append(append([]string{}, spec.Command...), spec.Args...)
+			}
+			d.addLogs(ctx, evidence, status.Name, false)
+			d.add(PodFinding{
+				Category:  FindingInitContainerStuck,
+				Container: status.Name,
+				Summary:   fmt.Sprintf("init container %s has not completed, the app containers cannot start until it does", status.Name),
+				LikelyCauses: []string{
+					"it is waiting for a dependency (a Service, database or DNS name) that is not available, check its logs",
+					"its command does not exit",
+				},
+				Evidence: evidence,
+			})
+		}
+		// 前一个init容器没有完成时，后面的还没有运行
+		return
+	}
+}
+
+func (d *podDiagnoser) checkContainers(ctx context.Context) {
+	for _, status := range d.pod.Status.ContainerStatuses {
+		d.checkContainer(ctx, status, false)
+	}
+}
+
+// checkContainer 检查单个容器的等待和退出状态，发现问题时返回true
+func (d *podDiagnoser) checkContainer(ctx context.Context, status corev1.ContainerStatus, init bool) bool {
+	kind := "container"
+	if init {
+		kind = "init container"
+	}
+	spec := d.containerSpec(status.Name)
+	if waiting := status.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+			d.add(d.imagePullFinding(status, spec, kind))
+			return true
+		case "CreateContainerConfigError", "CreateContainerError", "RunContainerError":
+			d.add(PodFinding{
+				Category:     FindingContainerConfig,
+				Container:    status.Name,
+				Summary:      fmt.Sprintf("%s %s cannot be created: %s", kind, status.Name, waiting.Reason),
+				LikelyCauses: configErrorCauses(waiting.Message),
+				Evidence:     map[string]interface{}{"reason": waiting.Reason, "message": waiting.Message},
+			})
+			return true
+		case "CrashLoopBackOff":
+			d.add(d.crashFinding(ctx, status, spec, kind))
+			return true
+		}
+	}
+	if terminated := status.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		d.add(d.oomFinding(ctx, status, spec, kind, terminated))
+		return true
+	}
+	if last := status.LastTerminationState.Terminated; last != nil && last.Reason == "OOMKilled" {
+		d.add(d.oomFinding(ctx, status, spec, kind, last))
+		return true
+	}
+	// restartPolicy为Never或OnFailure时失败的容器不会进入CrashLoopBackOff
+	if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+		evidence := map[string]interface{}{"state": containerStateString(status.State)}
+		d.addLogs(ctx, evidence, status.Name, false)
+		d.add(PodFinding{
+			Category:     FindingContainerFailed,
+			Container:    status.Name,
+			Summary:      fmt.Sprintf("%s %s exited with code %d", kind, status.Name, terminated.ExitCode),
+			LikelyCauses: exitCodeCauses(terminated),
+			Evidence:     evidence,
+		})
+		return true
+	}
+	return false
+}
+
+func (d *podDiagnoser) imagePullFinding(status corev1.ContainerStatus, spec *corev1.Container, kind string) PodFinding {
+	waiting := status.State.Waiting
+	messages := d.eventMessages("Failed", "ErrImagePull", "BackOff")
+	var relevant []string
+	for _, message := range messages {
+		if strings.Contains(message, "image") || strings.Contains(message, "pull") {
+			relevant = append(relevant, message)
+		}
+	}
+	evidence := map[string]interface{}{
+		"image":   status.Image,
+		"reason":  waiting.Reason,
+		"message": waiting.Message,
+	}
+	if spec != nil {
+		evidence["image"] = spec.Image
+		evidence["imagePullPolicy"] = spec.ImagePullPolicy
+	}
+	if len(d.pod.Spec.ImagePullSecrets) > 0 {
+		var secrets []string
+		for _, secret := range d.pod.Spec.ImagePullSecrets {
+			secrets = append(secrets, secret.Name)
+		}
+		evidence["imagePullSecrets"] = secrets
+	}
+	if len(relevant) > 0 {
+		evidence["events"] = relevant
+	}
+	return PodFinding{
+		Category:     FindingImagePullBackOff,
+		Container:    status.Name,
+		Summary:      fmt.Sprintf("%s %s cannot pull its image %v: %s", kind, status.Name, evidence["image"], waiting.Reason),
+		LikelyCauses: imagePullCauses(waiting.Reason, strings.Join(append([]string{waiting.Message}, relevant...), "\n"), len(d.pod.Spec.ImagePullSecrets) > 0),
+		Evidence:     evidence,
+	}
+}
+
+// imagePullCauses 根据拉取镜像失败的消息给出原因
+func imagePullCauses(reason, message string, hasPullSecrets bool) []string {
+	switch reason {
+	case "InvalidImageName":
+		return []string{"the image reference is malformed, check the registry, repository and tag"}
+	case "ErrImageNeverPull":
+		return []string{"imagePullPolicy is Never and the image is not present on the node"}
+	}
+	lower := strings.ToLower(message)
+	var causes []string
+	if strings.Contains(lower, "not found") || strings.Contains(lower, "manifest unknown") || strings.Contains(lower, "does not exist") {
+		causes = append(causes, "the image or tag does not exist in the registry, check for a typo or an unpushed tag")
+	}
+	if strings.Contains(lower, "unauthorized") || strings.Contains(lower, "denied") || strings.Contains(lower, "authentication required") || strings.Contains(lower, "403") || strings.Contains(lower, "401") {
+		if hasPullSecrets {
+			causes = append(causes, "the registry rejected the credentials in imagePullSecrets, check that the secret is valid for this registry")
+		} else {
+			causes = append(causes, "the registry requires credentials, add imagePullSecrets to the pod or its service account")
+		}
+	}
+	if strings.Contains(lower, "toomanyrequests") || strings.Contains(lower, "rate limit") {
+		causes = append(causes, "the registry is rate limiting pulls, authenticate or use a mirror")
+	}
+	if strings.Contains(lower, "no such host") || strings.Contains(lower, "timeout") || strings.Contains(lower, "connection refused") || strings.Contains(lower, "dial tcp") || strings.Contains(lower, "tls") {
+		causes = append(causes, "the node cannot reach the registry (DNS, proxy, firewall or certificate problem)")
+	}
+	if len(causes) == 0 {
+		causes = []string{
+			"the image name or tag is wrong",
+			"the registry requires credentials (imagePullSecrets)",
+			"the node cannot reach the registry",
+		}
+	}
+	return causes
+}
+
+// configErrorCauses 根据容器创建失败的消息给出原因
+func configErrorCauses(message string) []string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "configmap"):
+		return []string{"a ConfigMap or key referenced by env, envFrom or a volume does not exist: " + message}
+	case strings.Contains(lower, "secret"):
+		return []string{"a Secret or key referenced by env, envFrom or a volume does not exist: " + message}
+	case strings.Contains(lower, "executable file not found") || strings.Contains(lower, "no such file"):
+		return []string{"the command or entrypoint does not exist in the image: " + message}
+	case strings.Contains(lower, "runasnonroot"):
+		return []string{"runAsNonRoot is set but the image runs as root, set runAsUser: " + message}
+	}
+	return []string{message}
+}
+
+func (d *podDiagnoser) crashFinding(ctx context.Context, status corev1.ContainerStatus, spec *corev1.Container, kind string) PodFinding {
+	last := status.LastTerminationState.Terminated
+	if last != nil && last.Reason == "OOMKilled" {
+		return d.oomFinding(ctx, status, spec, kind, last)
+	}
+	evidence := map[string]interface{}{
+		"restartCount": status.RestartCount,
+		"state":        containerStateString(status.State),
+	}
+	var causes []string
+	if last != nil {
+		evidence["lastState"] = containerStateString(status.LastTerminationState)
+		evidence["exitCode"] = last.ExitCode
+		if !last.StartedAt.IsZero() && !last.FinishedAt.IsZero() {
+			evidence["lastRunDuration"] = humanDuration(last.FinishedAt.Sub(last.StartedAt.Time))
+		}
+		causes = exitCodeCauses(last)
+	}
+	if spec != nil {
+		if len(spec.Command) > 0 || len(spec.Args) > 0 {
+			evidence["command"] = append(append([]string{}, spec.Command...), spec.Args...)
+		}
+		if spec.LivenessProbe != nil {
+			evidence["livenessProbe"] = probeString(spec.LivenessProbe)
+		}
+	}
+	if liveness := d.probeEvents("Liveness"); len(liveness) > 0 {
+		evidence["livenessProbeFailures"] = liveness
+		causes = append([]string{"the liveness probe fails and the kubelet restarts the container, see the ProbeFailure finding"}, causes...)
+	}
+	if logs := d.addLogs(ctx, evidence, status.Name, true); strings.TrimSpace(logs) == "" {
+		if _, failed := evidence["previousLogsError"]; !failed {
+			causes = append(causes, "the previous container wrote no logs, it may fail before the application starts (entrypoint or configuration)")
+		}
+	}
+	if len(causes) == 0 {
+		causes = []string{"the application keeps exiting, check the previous container logs"}
+	}
+	return PodFinding{
+		Category:     FindingCrashLoopBackOff,
+		Container:    status.Name,
+		Summary:      fmt.Sprintf("%s %s keeps crashing and has restarted %d times", kind, status.Name, status.RestartCount),
+		LikelyCauses: causes,
+		Evidence:     evidence,
+	}
+}
+
+// exitCodeCauses 根据退出码和原因推断容器退出的原因
+func exitCodeCauses(terminated *corev1.ContainerStateTerminated) []string {
+	switch terminated.Reason {
+	case "ContainerCannotRun", "StartError":
+		return []string{"the container runtime could not start the process: " + terminated.Message}
+	}
+	switch code := terminated.ExitCode; {
+	case code == 0:
+		return []string{"the main process exited successfully, but the pod's restartPolicy restarts it; the command may not run a long-lived process"}
+	case code == 126:
+		return []string{"the command is not executable (permission denied)"}
+	case code == 127:
+		return []string{"the command or entrypoint was not found in the image"}
+	case code == 137:
+		return []string{"the process was killed with SIGKILL, by the kubelet after a failed liveness probe or when it did not stop in time, or by the kernel OOM killer outside the container limit"}
+	case code == 139:
+		return []string{"the process crashed with a segmentation fault"}
+	case code == 143:
+		return []string{"the process exited on SIGTERM, usually because the kubelet stopped it after a failed liveness probe"}
+	case code > 128:
+		return []string{fmt.Sprintf("the process was killed by signal %d", code-128)}
+	default:
+		return []string{"the application exited with an error, check the previous container logs (bad configuration, missing environment variables or unreachable dependencies are common)"}
+	}
+}
+
+func (d *podDiagnoser) oomFinding(ctx context.Context, status corev1.ContainerStatus, spec *corev1.Container, kind string, terminated *corev1.ContainerStateTerminated) PodFinding {
+	evidence := map[string]interface{}{
+		"restartCount": status.RestartCount,
+		"killedAt":     terminated.FinishedAt.UTC().Format(time.RFC3339),
+	}
+	causes := []string{"the process uses more memory than the container limit, raise the limit or reduce the memory usage (heap settings, caches, leaks)"}
+	if spec != nil {
+		if limit, ok := spec.Resources.Limits[corev1.ResourceMemory]; ok {
+			evidence["memoryLimit"] = limit.String()
+		} else {
+			causes = []string{"the container has no memory limit, so it was killed because the node ran out of memory; set memory requests and limits"}
+		}
+		if request, ok := spec.Resources.Requests[corev1.ResourceMemory]; ok {
+			evidence["memoryRequest"] = request.String()
+		}
+	}
+	previous := status.State.Terminated == nil
+	d.addLogs(ctx, evidence, status.Name, previous)
+	return PodFinding{
+		Category:     FindingOOMKilled,
+		Container:    status.Name,
+		Summary:      fmt.Sprintf("%s %s was killed because it ran out of memory", kind, status.Name),
+		LikelyCauses: causes,
+		Evidence:     evidence,
+	}
+}
+
+// probeEvents 返回某种探针（Liveness、Readiness、Startup）失败的事件消息
+func (d *podDiagnoser) probeEvents(probe string) []string {
+	var messages []string
+	for _, message := range d.eventMessages("Unhealthy") {
+		if strings.HasPrefix(message, probe) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (d *podDiagnoser) checkProbes() {
+	messages := d.eventMessages("Unhealthy")
+	if len(messages) == 0 {
+		return
+	}
+	// 只在容器当前不健康时报告，已经恢复的探针失败只是历史
+	var notReady []string
+	for _, status := range d.pod.Status.ContainerStatuses {
+		if !status.Ready || status.RestartCount > 0 {
+			notReady = append(notReady, status.Name)
+		}
+	}
+	if len(notReady) == 0 {
+		return
+	}
+	probes := map[string]interface{}{}
+	for _, container := range d.pod.Spec.Containers {
+		configured := map[string]string{}
+		if container.LivenessProbe != nil {
+			configured["liveness"] = probeString(container.LivenessProbe)
+		}
+		if container.ReadinessProbe != nil {
+			configured["readiness"] = probeString(container.ReadinessProbe)
+		}
+		if container.StartupProbe != nil {
+			configured["startup"] = probeString(container.StartupProbe)
+		}
+		if len(configured) > 0 {
+			probes[container.Name] = configured
+		}
+	}
+	joined := strings.ToLower(strings.Join(messages, "\n"))
+	var causes []string
+	switch {
+	case strings.Contains(joined, "connection refused"):
+		causes = append(causes, "nothing listens on the probe port, the application may listen on another port or address, or it has not started yet")
+	case strings.Contains(joined, "timeout") || strings.Contains(joined, "deadline exceeded"):
+		causes = append(causes, "the probe endpoint responds slower than timeoutSeconds, the application may be overloaded or blocked")
+	case strings.Contains(joined, "statuscode: 404"):
+		causes = append(causes, "the probe path does not exist (HTTP 404)")
+	case strings.Contains(joined, "statuscode: 5"):
+		causes = append(causes, "the probe endpoint returns a server error, the application reports itself unhealthy (often a failing dependency)")
+	}
+	causes = append(causes, "the application starts slower than initialDelaySeconds or a startupProbe allows")
+	d.add(PodFinding{
+		Category:     FindingProbeFailure,
+		Summary:      fmt.Sprintf("health probes are failing for %s", strings.Join(notReady, ", ")),
+		LikelyCauses: causes,
+		Evidence: map[string]interface{}{
+			"events": messages,
+			"probes": probes,
+		},
+	})
+}
+
+// checkVolumes 检查卷挂载失败，这时容器一直停在ContainerCreating
+func (d *podDiagnoser) checkVolumes() {
+	if d.pod.Status.Phase != corev1.PodPending {
+		return
+	}
+	messages := d.eventMessages("FailedMount", "FailedAttachVolume")
+	if len(messages) == 0 {
+		return
+	}
+	joined := strings.ToLower(strings.Join(messages, "\n"))
+	var causes []string
+	switch {
+	case strings.Contains(joined, "not found"):
+		causes = append(causes, "a ConfigMap, Secret or PersistentVolumeClaim used as a volume does not exist")
+	case strings.Contains(joined, "multi-attach"):
+		causes = append(causes, "the volume is still attached to another node (ReadWriteOnce), wait for the old pod to be removed or detach it")
+	case strings.Contains(joined, "timed out"):
+		causes = append(causes, "the volume could not be attached or mounted in time, check the CSI driver and the storage backend")
+	}
+	if len(causes) == 0 {
+		causes = []string{"the volume cannot be mounted, see the events"}
+	}
+	d.add(PodFinding{
+		Category:     FindingVolumeMount,
+		Summary:      "the pod's volumes cannot be mounted, so its containers cannot start",
+		LikelyCauses: causes,
+		Evidence:     map[string]interface{}{"events": messages},
+	})
+}
+
+func (d *podDiagnoser) checkNode() {
+	if d.node == nil {
+		return
+	}
+	var problems []string
+	for _, condition := range d.node.Status.Conditions {
+		switch {
+		case condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue:
+			problems = append(problems, fmt.Sprintf("node is not ready (%s: %s)", condition.Reason, condition.Message))
+		case condition.Type != corev1.NodeReady && condition.Status == corev1.ConditionTrue:
+			problems = append(problems, fmt.Sprintf("%s: %s", condition.Type, condition.Message))
+		}
+	}
+	if len(problems) == 0 {
+		return
+	}
+	evidence := map[string]interface{}{"node": d.node.Name, "problems": problems}
+	if d.node.Spec.Unschedulable {
+		evidence["cordoned"] = true
+	}
+	d.add(PodFinding{
+		Category: FindingNodeProblem,
+		Summary:  fmt.Sprintf("node %s that runs the pod is unhealthy", d.node.Name),
+		LikelyCauses: []string{
+			"the node's kubelet, container runtime or network is unhealthy, or the node is under resource pressure; pods on it may be evicted or stop responding",
+		},
+		Evidence: evidence,
+	})
+}
+
+// nodeConditions 返回节点的conditions，按类型排序
+func nodeConditions(node *corev1.Node) []map[string]interface{} {
+	conditions := make([]map[string]interface{}, 0, len(node.Status.Conditions))
+	for _, condition := range node.Status.Conditions {
+		conditions = append(conditions, map[string]interface{}{
+			"type":    string(condition.Type),
+			"status":  string(condition.Status),
+			"reason":  condition.Reason,
+			"message": condition.Message,
+		})
+	}
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i]["type"].(string) < conditions[j]["type"].(string)
+	})
+	return conditions
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodesOverview 汇总集群中节点的状态，用于解释调度失败
+func (c *Client) nodesOverview(ctx context.Context) (map[string]interface{}, error) {
+	var nodes []corev1.Node
+	if cached, found := c.listResourcesFromCache("Node", "", "", ""); found {
+		for _, item := range cached {
+			node := corev1.Node{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &node); err == nil {
+				nodes = append(nodes, node)
+			}
+		}
+	} else {
+		list, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		nodes = list.Items
+	}
+	ready := 0
+	var notReady, cordoned []string
+	taints := map[string][]string{}
+	for _, node := range nodes {
+		if nodeReady(&node) {
+			ready++
+		} else {
+			notReady = append(notReady, node.Name)
+		}
+		if node.Spec.Unschedulable {
+			cordoned = append(cordoned, node.Name)
+		}
+		for _, taint := range node.Spec.Taints {
+			taints[node.Name] = append(taints[node.Name], taint.ToString())
+		}
+	}
+	overview := map[string]interface{}{
+		"total": len(nodes),
+		"ready": ready,
+	}
+	if len(notReady) > 0 {
+		overview["notReady"] = notReady
+	}
+	if len(cordoned) > 0 {
+		overview["cordoned"] = cordoned
+	}
+	if len(taints) > 0 {
+		overview["taints"] = taints
+	}
+	return overview, nil
+}
+
+// podLooksHealthy 判断没有发现问题的Pod是否真的健康：正在运行并且容器都ready，或者已经成功结束
+func podLooksHealthy(pod *corev1.Pod) bool {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true
+	case corev1.PodRunning:
+		for _, status := range pod.Status.ContainerStatuses {
+			if !status.Ready {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
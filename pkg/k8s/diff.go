@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange 描述两个对象之间某一个字段的差异
+// Op 为 add、remove 或 change；新增或删除整个子树时只记录一条，不会继续展开
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// 比较对象时忽略的字段，这些字段每次写入都会变化，对审阅变更没有帮助
+var diffIgnoredPaths = map[string]struct{}{
+	"status":                     {},
+	"metadata.managedFields":     {},
+	"metadata.resourceVersion":   {},
+	"metadata.generation":        {},
+	"metadata.uid":               {},
+	"metadata.creationTimestamp": {},
+	"metadata.annotations.kubectl.kubernetes.io/last-applied-configuration": {},
+}
+
+// 推送对象变化时忽略的字段，和diffIgnoredPaths不同，status的变化也需要推送
+var watchIgnoredPaths = map[string]struct{}{
+	"metadata.managedFields":   {},
+	"metadata.resourceVersion": {},
+}
+
+// DiffObjects 比较两个unstructured对象，返回按路径排序的字段差异
+// oldObj 为 nil 时表示对象还不存在
+func DiffObjects(oldObj, newObj map[string]interface{}) []FieldChange {
+	return diffObjects(oldObj, newObj, diffIgnoredPaths)
+}
+
+func diffObjects(oldObj, newObj map[string]interface{}, ignored map[string]struct{}) []FieldChange {
+	changes := []FieldChange{}
+	diffValue("", oldObj, newObj, ignored, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValue(path string, oldVal, newVal interface{}, ignored map[string]struct{}, changes *[]FieldChange) {
+	if _, skip := ignored[path]; skip {
+		return
+	}
+	oldMap, oldIsMap := oldVal.(map[string]interface{})
+	newMap, newIsMap := newVal.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := map[string]struct{}{}
+		for k := range oldMap {
+			keys[k] = struct{}{}
+		}
+		for k := range newMap {
+			keys[k] = struct{}{}
+		}
+		for k := range keys {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			oldChild, inOld := oldMap[k]
+			newChild, inNew := newMap[k]
+			switch {
+			case !inOld:
+				if _, skip := ignored[childPath]; !skip {
+					*changes = append(*changes, FieldChange{Path: childPath, Op: "add", New: pruneIgnored(childPath, newChild, ignored)})
+				}
+			case !inNew:
+				if _, skip := ignored[childPath]; !skip {
+					*changes = append(*changes, FieldChange{Path: childPath, Op: "remove", Old: pruneIgnored(childPath, oldChild, ignored)})
+				}
+			default:
+				diffValue(childPath, oldChild, newChild, ignored, changes)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := oldVal.([]interface{})
+	newList, newIsList := newVal.([]interface{})
+	if oldIsList && newIsList && len(oldList) == len(newList) {
+		for i := range oldList {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), oldList[i], newList[i], ignored, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldVal, newVal) {
+		switch {
+		case oldVal == nil:
+			*changes = append(*changes, FieldChange{Path: path, Op: "add", New: newVal})
+		case newVal == nil:
+			*changes = append(*changes, FieldChange{Path: path, Op: "remove", Old: oldVal})
+		default:
+			*changes = append(*changes, FieldChange{Path: path, Op: "change", Old: oldVal, New: newVal})
+		}
+	}
+}
+
+// pruneIgnored 返回去掉了被忽略字段的子树副本，用于整体新增或删除的子树
+func pruneIgnored(path string, val interface{}, ignored map[string]struct{}) interface{} {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return val
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		childPath := k
+		if path != "" {
+			childPath = path + "." + k
+		}
+		if _, skip := ignored[childPath]; skip {
+			continue
+		}
+		out[k] = pruneIgnored(childPath, v, ignored)
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// ExecOptions 控制在容器中执行命令的方式
+type ExecOptions struct {
+	// Container 为空时使用pod的第一个容器
+	Container string
+	// Timeout 超时后命令会被中断
+	Timeout time.Duration
+	// MaxOutputBytes 是stdout和stderr各自最多保留的字节数，超出部分会被截断
+	MaxOutputBytes int
+}
+
+// IsCommandAllowed 判断命令是否和某个允许的命令相同，允许的命令按空白切分后逐个参数比较
+// 默认要求参数完全相同，例如 "cat /etc/resolv.conf" 只允许读取这个文件，不能再附加其他文件；
+// 最后一个字段为 * 时允许之后有任意参数，例如 "ps *" 允许 ps aux
+func IsCommandAllowed(command []string, allowedCommands []string) bool {
+	for _, allowed := range allowedCommands {
+		fields := strings.Fields(allowed)
+		anyArgs := len(fields) > 0 && fields[len(fields)-1] == "*"
+		if anyArgs {
+			fields = fields[:len(fields)-1]
+		}
+		if len(fields) == 0 || len(fields) > len(command) || (!anyArgs && len(fields) != len(command)) {
+			continue
+		}
+		matched := true
+		for i, field := range fields {
+			if command[i] != field {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBuffer 只保留前limit个字节，超出的部分丢弃并标记为截断
+// 超时返回后stream可能仍在写入，所以读写都需要加锁
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+	lock      sync.Mutex
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = b.truncated || len(p) > 0
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) result() (string, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String(), b.truncated
+}
+
+// ExecInPod 在pod的容器中直接执行命令（不经过shell），返回stdout、stderr以及退出码
+// 优先使用WebSocket协议，API Server不支持时回退到SPDY
+func (c *Client) ExecInPod(ctx context.Context, namespace, podName string, command []string, opts ExecOptions) (map[string]interface{}, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	req := c.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	spdyExec, err := remotecommand.NewSPDYExecutor(c.restConfig, "POST", req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spdy executor: %w", err)
+	}
+	websocketExec, err := remotecommand.NewWebSocketExecutor(c.restConfig, "GET", req.URL().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create websocket executor: %w", err)
+	}
+	executor, err := remotecommand.NewFallbackExecutor(websocketExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	stdout := &limitedBuffer{limit: opts.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: opts.MaxOutputBytes}
+	start := time.Now()
+	err = executor.StreamWithContext(execCtx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+
+	stdoutText, stdoutTruncated := stdout.result()
+	stderrText, stderrTruncated := stderr.result()
+	result := map[string]interface{}{
+		"pod":             podName,
+		"namespace":       namespace,
+		"container":       opts.Container,
+		"command":         command,
+		"stdout":          stdoutText,
+		"stderr":          stderrText,
+		"stdoutTruncated": stdoutTruncated,
+		"stderrTruncated": stderrTruncated,
+		"exitCode":        0,
+		"duration":        time.Since(start).String(),
+	}
+	if err != nil {
+		// 命令以非0退出码结束不算执行失败
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) {
+			result["exitCode"] = exitErr.ExitStatus()
+			return result, nil
+		}
+		if execCtx.Err() == context.DeadlineExceeded {
+			result["exitCode"] = nil
+			result["timedOut"] = true
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to exec in pod %s/%s: %w", namespace, podName, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package k8s
+
+import "testing"
+
+func TestIsCommandAllowed(t *testing.T) {
+	allowed := []string{"cat /etc/resolv.conf", "cat /etc/hosts", "ls *", "ps *", "df", "nslookup *"}
+	tests := []struct {
+		name    string
+		command []string
+		want    bool
+	}{
+		{"exact multi-word", []string{"cat", "/etc/resolv.conf"}, true},
+		{"multi-word with extra file", []string{"cat", "/etc/resolv.conf", "/var/run/secrets/kubernetes.io/serviceaccount/token"}, false},
+		{"multi-word with other file", []string{"cat", "/etc/shadow"}, false},
+		{"multi-word prefix only", []string{"cat"}, false},
+		{"trailing args without args", []string{"ls"}, true},
+		{"trailing args with args", []string{"ps", "aux"}, true},
+		{"exact single word", []string{"df"}, true},
+		{"exact single word with args", []string{"df", "-h"}, false},
+		{"env is not allowed", []string{"env", "sh", "-c", "id"}, false},
+		{"curl is not allowed", []string{"curl", "-o", "/tmp/x", "file:///etc/passwd"}, false},
+		{"shell is not allowed", []string{"sh", "-c", "cat /etc/resolv.conf"}, false},
+		{"argument split differently", []string{"cat /etc/resolv.conf"}, false},
+		{"empty command", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCommandAllowed(tt.command, allowed); got != tt.want {
+				t.Errorf("IsCommandAllowed(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCommandAllowedIgnoresBareWildcard(t *testing.T) {
+	if IsCommandAllowed([]string{"sh", "-c", "id"}, []string{"*", ""}) {
+		t.Error("a bare * entry must not allow every command")
+	}
+}
@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// Identity 是调用方在Kubernetes中的身份，工具调用会通过ImpersonationConfig以这个身份执行
+// User和Groups都为空时使用服务器自己的身份
+type Identity struct {
+	User   string   `json:"user,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// IsZero 判断是否没有指定身份
+func (i Identity) IsZero() bool {
+	return i.User == "" && len(i.Groups) == 0
+}
+
+// String 返回用于日志和错误信息的写法，例如 alice(dev,ops)
+// 用户名和组名中可以有括号和逗号，不同的身份可能得到相同的结果，不能用作缓存的key
+func (i Identity) String() string {
+	if len(i.Groups) == 0 {
+		return i.User
+	}
+	groups := append([]string(nil), i.Groups...)
+	sort.Strings(groups)
+	return fmt.Sprintf("%s(%s)", i.User, strings.Join(groups, ","))
+}
+
+// cacheKey 返回区分所有身份的key：用户和排序后的组的JSON编码
+func (i Identity) cacheKey() string {
+	groups := append([]string{}, i.Groups...)
+	sort.Strings(groups)
+	key, _ := json.Marshal(Identity{User: i.User, Groups: groups})
+	return string(key)
+}
+
+type identityKey struct{}
+
+// WithIdentity 返回带有调用方身份的context，Registry.ClientFor会按这个身份选择客户端
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext 返回context中的调用方身份
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok && !identity.IsZero()
+}
+
+// newImpersonatedClient 基于base的rest config创建一个模拟identity的客户端
+// informer缓存是用服务器自己的身份填充的，模拟的客户端不能读取它们，所以这里不注册任何informer，
+// 所有的读取都会以模拟的身份直接访问API Server；资源类型的解析结果从base复制一份
+func newImpersonatedClient(base *Client, identity Identity) (*Client, error) {
+	// API Server不允许只模拟组
+	if identity.User == "" {
+		return nil, fmt.Errorf("groups %v can not be impersonated without a user", identity.Groups)
+	}
+	config := rest.CopyConfig(base.restConfig)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: identity.User,
+		Groups:   identity.Groups,
+	}
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	client.base = base
+	client.identity = identity
+
+	base.cacheLock.RLock()
+	defer base.cacheLock.RUnlock()
+	for key, resource := range base.apiResources {
+		client.apiResources[key] = resource
+	}
+	return client, nil
+}
+
+// checkWatchAccess 检查模拟的身份是否可以list和watch要订阅的资源
+// 订阅复用base的informer，没有这一步检查的话任何身份都可以通过订阅看到所有对象
+func (c *Client) checkWatchAccess(ctx context.Context, opts WatchOptions) error {
+	kinds := []string{opts.Kind}
+	if opts.WarningEvents {
+		kinds = append(kinds, "Event")
+	}
+	for _, kind := range kinds {
+		for _, verb := range []string{"list", "watch"} {
+			result, err := c.CanI(ctx, AccessCheck{Verb: verb, Kind: kind, Namespace: opts.Namespace})
+			if err != nil {
+				return err
+			}
+			if allowed, _ := result["allowed"].(bool); !allowed {
+				scope := "all namespaces"
+				if opts.Namespace != "" {
+					scope = "namespace " + opts.Namespace
+				}
+				return fmt.Errorf("%s is not allowed to %s %s in %s", c.identity, verb, result["resource"], scope)
+			}
+		}
+	}
+	return nil
+}
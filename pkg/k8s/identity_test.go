@@ -0,0 +1,31 @@
+package k8s
+
+import "testing"
+
+func TestIdentityCacheKey(t *testing.T) {
+	distinct := []Identity{
+		{User: "a(b)"},
+		{User: "a", Groups: []string{"b"}},
+		{User: "alice(system:masters)"},
+		{User: "alice", Groups: []string{"system:masters"}},
+		{User: "alice", Groups: []string{"x,y"}},
+		{User: "alice", Groups: []string{"x", "y"}},
+	}
+	seen := map[string]Identity{}
+	for _, identity := range distinct {
+		key := identity.cacheKey()
+		if other, ok := seen[key]; ok {
+			t.Errorf("identities %#v and %#v have the same cache key %s", other, identity, key)
+		}
+		seen[key] = identity
+	}
+
+	a := Identity{User: "alice", Groups: []string{"ops", "dev"}}
+	b := Identity{User: "alice", Groups: []string{"dev", "ops"}}
+	if a.cacheKey() != b.cacheKey() {
+		t.Errorf("group order changed the cache key: %s != %s", a.cacheKey(), b.cacheKey())
+	}
+	if a.Groups[0] != "ops" {
+		t.Errorf("cacheKey() reordered the identity's groups: %v", a.Groups)
+	}
+}
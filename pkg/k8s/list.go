@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ListPage 控制列表的分页，Limit为0时返回全部结果
+type ListPage struct {
+	Limit int64
+	// Continue 是上一页返回的token，为空时从第一页开始
+	Continue string
+}
+
+// ListResult 是一页列表结果，Continue为空表示没有下一页
+// Total 是所有页的总数，API Server没有返回剩余数量时为nil
+// 设置了jsonPath时Items为空，渲染结果在Output中
+type ListResult struct {
+	Items    []map[string]interface{} `json:"items,omitempty"`
+	Output   string                   `json:"output,omitempty"`
+	Total    *int64                   `json:"total,omitempty"`
+	Continue string                   `json:"continue,omitempty"`
+}
+
+// 缓存和API Server的分页方式不同，token中记录了它来自哪一边：
+// 缓存按 namespace/name 排序，记录上一页最后一个对象，这样翻页期间新增或删除对象不会导致重复或遗漏
+// API Server的continue token是不透明的，额外记录已经返回的数量用于计算总数
+const (
+	listSourceCache = "cache"
+	listSourceAPI   = "api"
+)
+
+type listToken struct {
+	Source string `json:"s"`
+	// After 是缓存分页中上一页最后一个对象的 namespace/name
+	After string `json:"a,omitempty"`
+	// Offset 是API Server分页中已经返回的数量
+	Offset int64 `json:"o,omitempty"`
+	// Token 是API Server返回的continue token
+	Token string `json:"t,omitempty"`
+}
+
+func encodeListToken(token listToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListToken(value string) (*listToken, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continue token")
+	}
+	token := &listToken{}
+	if err := json.Unmarshal(data, token); err != nil || (token.Source != listSourceCache && token.Source != listSourceAPI) {
+		return nil, fmt.Errorf("invalid continue token")
+	}
+	return token, nil
+}
+
+func listItemKey(obj *unstructured.Unstructured) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// paginateCached 对缓存中过滤后的对象排序并取出after之后的一页，返回这一页、总数和下一页的token
+func paginateCached(objects []*unstructured.Unstructured, page ListPage, after string) ([]*unstructured.Unstructured, int64, string) {
+	sort.Slice(objects, func(i, j int) bool {
+		return listItemKey(objects[i]) < listItemKey(objects[j])
+	})
+	total := int64(len(objects))
+	start := sort.Search(len(objects), func(i int) bool {
+		return listItemKey(objects[i]) > after
+	})
+	objects = objects[start:]
+	if page.Limit <= 0 || int64(len(objects)) <= page.Limit {
+		return objects, total, ""
+	}
+	objects = objects[:page.Limit]
+	next := encodeListToken(listToken{
+		Source: listSourceCache,
+		After:  listItemKey(objects[len(objects)-1]),
+	})
+	return objects, total, next
+}
+
+// buildListResult 把一页对象转换为返回结果
+// 没有投影时每个对象只返回名称、命名空间和标签；设置了jsonPath时模板作用于整个列表，和kubectl一致
+func buildListResult(kind string, objects []*unstructured.Unstructured, projection Projection) (*ListResult, error) {
+	result := &ListResult{Items: []map[string]interface{}{}}
+	if projection.JSONPath != "" {
+		items := make([]interface{}, 0, len(objects))
+		for _, obj := range objects {
+			items = append(items, obj.Object)
+		}
+		output, err := executeJSONPath(projection.JSONPath, map[string]interface{}{"kind": "List", "items": items})
+		if err != nil {
+			return nil, err
+		}
+		result.Items = nil
+		result.Output = output
+		return result, nil
+	}
+	for _, obj := range objects {
+		if len(projection.Fields) > 0 {
+			item, err := projectFields(projection.Fields, obj.Object)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, item)
+			continue
+		}
+		result.Items = append(result.Items, map[string]interface{}{
+			"name":      obj.GetName(),
+			"kind":      kind,
+			"namespace": obj.GetNamespace(),
+			"lables":    obj.GetLabels(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 日志默认的字节预算，超过时丢弃最早的行，保留最新的日志
+const DefaultLogBytes = 64 * 1024
+
+// 单次请求允许的最大字节预算
+const MaxLogBytes = 1024 * 1024
+
+// grep 上下文行数的上限
+const maxLogContextLines = 20
+
+// PodLogOptions 是读取Pod日志的选项
+type PodLogOptions struct {
+	// Container 为空时读取所有容器
+	Container string
+	// TailLines 是从服务器读取的最后几行，0表示全部（仍然受SinceSeconds/SinceTime和MaxBytes限制）
+	TailLines int64
+	// Previous 读取上一次退出的容器的日志，用于排查崩溃
+	Previous bool
+	// SinceSeconds 和 SinceTime（RFC3339）只能设置一个
+	SinceSeconds int64
+	SinceTime    string
+	// Timestamps 在每行前加上RFC3339格式的时间
+	Timestamps bool
+	// Grep 是正则表达式，只返回匹配的行以及前后ContextLines行
+	Grep         string
+	ContextLines int
+	// MaxBytes 是返回日志的字节预算，0表示DefaultLogBytes
+	MaxBytes int64
+}
+
+func (o PodLogOptions) toPodLogOptions() (*corev1.PodLogOptions, error) {
+	options := &corev1.PodLogOptions{
+		Previous:   o.Previous,
+		Timestamps: o.Timestamps,
+	}
+	if o.TailLines > 0 {
+		tailLines := o.TailLines
+		options.TailLines = &tailLines
+	}
+	if o.SinceSeconds != 0 && o.SinceTime != "" {
+		return nil, fmt.Errorf("sinceSeconds and sinceTime cannot be used together")
+	}
+	if o.SinceSeconds < 0 {
+		return nil, fmt.Errorf("sinceSeconds must be positive")
+	}
+	if o.SinceSeconds > 0 {
+		sinceSeconds := o.SinceSeconds
+		options.SinceSeconds = &sinceSeconds
+	}
+	if o.SinceTime != "" {
+		since, err := time.Parse(time.RFC3339, o.SinceTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinceTime %q, use RFC3339 like 2024-01-02T15:04:05Z: %w", o.SinceTime, err)
+		}
+		sinceTime := metav1.NewTime(since)
+		options.SinceTime = &sinceTime
+	}
+	if o.MaxBytes > MaxLogBytes {
+		return nil, fmt.Errorf("maxBytes must not exceed %d", MaxLogBytes)
+	}
+	return options, nil
+}
+
+// logFilter 和 grep -C 一样输出匹配的行及其上下文，不连续的片段之间用 -- 分隔
+type logFilter struct {
+	pattern *regexp.Regexp
+	context int
+	// timestamped 表示每行前有服务器加上的时间戳，匹配时去掉它，^ERROR 这样的模式才能匹配
+	timestamped bool
+}
+
+func newLogFilter(grep string, contextLines int, timestamped bool) (*logFilter, error) {
+	if grep == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(grep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern %q: %w", grep, err)
+	}
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if contextLines > maxLogContextLines {
+		contextLines = maxLogContextLines
+	}
+	return &logFilter{pattern: pattern, context: contextLines, timestamped: timestamped}, nil
+}
+
+// matches 用一行日志（不包括换行符）的内容匹配模式
+func (f *logFilter) matches(line string) bool {
+	if f.timestamped {
+		if ts, message, found := strings.Cut(line, " "); found {
+			if _, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line = message
+			}
+		}
+	}
+	return f.pattern.MatchString(line)
+}
+
+// logBuffer 只保留最后maxBytes字节的完整行
+type logBuffer struct {
+	maxBytes int64
+	lines    []string
+	size     int64
+	dropped  int64
+}
+
+func (b *logBuffer) add(line string) {
+	if int64(len(line)) > b.maxBytes {
+		b.dropped += int64(len(line)) - b.maxBytes
+		line = line[int64(len(line))-b.maxBytes:]
+	}
+	b.lines = append(b.lines, line)
+	b.size += int64(len(line))
+	for b.size > b.maxBytes && len(b.lines) > 0 {
+		b.size -= int64(len(b.lines[0]))
+		b.dropped += int64(len(b.lines[0]))
+		b.lines = b.lines[1:]
+	}
+}
+
+func (b *logBuffer) String() string {
+	var out strings.Builder
+	if b.dropped > 0 {
+		fmt.Fprintf(&out, "... %d earlier bytes omitted, narrow the query with tailLines, sinceSeconds or grep ...\n", b.dropped)
+	}
+	for _, line := range b.lines {
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+// readPodLogs 流式读取单个容器的日志，按filter过滤后只保留最后maxBytes字节
+func (c *Client) readPodLogs(ctx context.Context, namespace, podName string, options *corev1.PodLogOptions, filter *logFilter, maxBytes int64) (string, error) {
+	stream, err := c.Clientset.CoreV1().Pods(namespace).GetLogs(podName, options).Stream(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	return filterLogs(stream, filter, maxBytes)
+}
+
+// filterLogs 逐行读取日志，filter为nil时保留所有行
+func filterLogs(stream io.Reader, filter *logFilter, maxBytes int64) (string, error) {
+	buffer := &logBuffer{maxBytes: maxBytes}
+	// before 是最近还没有输出的行，用作下一次匹配的前置上下文
+	var before []string
+	after := 0
+	lastEmitted, lineNumber := 0, 0
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lineNumber++
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			switch {
+			case filter == nil:
+				buffer.add(line)
+			case filter.matches(strings.TrimSuffix(line, "\n")):
+				if lastEmitted > 0 && lineNumber-len(before) > lastEmitted+1 {
+					buffer.add("--\n")
+				}
+				for _, previous := range before {
+					buffer.add(previous)
+				}
+				before = before[:0]
+				buffer.add(line)
+				lastEmitted = lineNumber
+				after = filter.context
+			case after > 0:
+				buffer.add(line)
+				lastEmitted = lineNumber
+				after--
+			case filter.context > 0:
+				before = append(before, line)
+				if len(before) > filter.context {
+					before = before[1:]
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read logs: %w", err)
+		}
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,38 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterLogsIgnoresTimestamps(t *testing.T) {
+	logs := "2024-05-01T08:30:00.000000001Z INFO starting\n" +
+		"2024-05-01T08:30:01.000000001Z ERROR failed to connect\n" +
+		"2024-05-01T08:30:02.000000001Z INFO retrying ERROR count=1\n"
+	filter, err := newLogFilter("^ERROR", 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := filterLogs(strings.NewReader(logs), filter, DefaultLogBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-05-01T08:30:01.000000001Z ERROR failed to connect\n"; got != want {
+		t.Errorf("filterLogs() = %q, want %q", got, want)
+	}
+}
+
+func TestMergeLogStreamsKeepsTruncationNotes(t *testing.T) {
+	streams := []logStream{
+		{prefix: "web-1", logs: "... 120 earlier bytes omitted, narrow the query with tailLines, sinceSeconds or grep ...\n" +
+			"2024-05-01T08:30:02Z second\n"},
+		{prefix: "web-2", logs: "2024-05-01T08:30:01Z first\n--\n2024-05-01T08:30:03Z third\n"},
+	}
+	lines, notes := mergeLogStreams(streams, false)
+	if want := []string{"[web-2] first\n", "[web-1] second\n", "[web-2] third\n"}; strings.Join(lines, "") != strings.Join(want, "") {
+		t.Errorf("mergeLogStreams() lines = %q, want %q", lines, want)
+	}
+	if len(notes) != 1 || !strings.HasPrefix(notes[0], "[web-1] ... 120 earlier bytes omitted") {
+		t.Errorf("mergeLogStreams() notes = %q, want the truncation note of web-1", notes)
+	}
+}
@@ -0,0 +1,279 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// 多文档manifest中对象的apply顺序，被依赖的资源排在前面，工作负载排在后面
+// 不在列表中的kind（一般是自定义资源）排在最后，这样它们的CRD已经先被创建
+var installOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PriorityClass",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// 等待CRD变为Established的最长时间
+const crdEstablishTimeout = 30 * time.Second
+
+// manifestObject 是从多文档manifest中解析出的一个对象，index为它在原文件中的位置（从0开始）
+type manifestObject struct {
+	index int
+	obj   *unstructured.Unstructured
+}
+
+// splitManifests 按 --- 切分多文档YAML（也兼容JSON），跳过空文档，并展开 kind: List
+// index按reader返回的每个文档计数，包括跳过的只有注释或空白的文档，这样和原文件中的位置一致
+func splitManifests(manifests string) ([]manifestObject, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifests)))
+	var objects []manifestObject
+	for index := 0; ; index++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read yaml document %d: %w", index, err)
+		}
+		jsonData, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve yaml document %d: %w", index, err)
+		}
+		// 只有注释或空白的文档
+		if len(jsonData) == 0 || string(jsonData) == "null" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(jsonData, &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse yaml document %d: %w", index, err)
+		}
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, manifestObject{index: index, obj: item.(*unstructured.Unstructured)})
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse list in yaml document %d: %w", index, err)
+			}
+		} else {
+			objects = append(objects, manifestObject{index: index, obj: obj})
+		}
+	}
+	return objects, nil
+}
+
+// ObjectRef 是一次调用要写入的对象，Kind是规范名称，集群级别的对象Namespace为空
+type ObjectRef struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ManifestRefs 解析manifest中的所有对象，按apply时相同的规则确定它们的命名空间：
+// override为true时（createResourceJSON/YAML）namespace覆盖对象自己的命名空间，否则只用于没有命名空间的对象，都没有时为default
+// apply会自动创建不存在的命名空间，所以这些命名空间（manifest中没有定义时）也作为Namespace对象返回
+func (c *Client) ManifestRefs(ctx context.Context, manifests, namespace string, override bool) ([]ObjectRef, error) {
+	objects, err := splitManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]ObjectRef, 0, len(objects))
+	for _, item := range objects {
+		obj := item.obj
+		ref := ObjectRef{Kind: obj.GetKind(), Name: obj.GetName()}
+		namespaced := true
+		if resource, err := c.resolveGVK(obj.GetAPIVersion(), obj.GetKind()); err == nil {
+			ref.Kind = resource.key
+			namespaced = resource.namespaced
+		}
+		if namespaced {
+			ref.Namespace = obj.GetNamespace()
+			if namespace != "" && (override || ref.Namespace == "") {
+				ref.Namespace = namespace
+			}
+			if ref.Namespace == "" {
+				ref.Namespace = metav1.NamespaceDefault
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	declared := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Kind == "Namespace" {
+			declared[ref.Name] = true
+		}
+	}
+	for _, ref := range refs {
+		if ref.Namespace == "" || declared[ref.Namespace] {
+			continue
+		}
+		declared[ref.Namespace] = true
+		// 无法确认时也当作不存在，让策略检查创建命名空间
+		if _, err := c.Clientset.CoreV1().Namespaces().Get(ctx, ref.Namespace, metav1.GetOptions{}); err == nil {
+			continue
+		}
+		refs = append(refs, ObjectRef{Kind: "Namespace", Name: ref.Namespace})
+	}
+	return refs, nil
+}
+
+// sortByInstallOrder 按installOrder对对象做稳定排序，同类对象保持原文件中的顺序
+func sortByInstallOrder(objects []manifestObject) {
+	rank := make(map[string]int, len(installOrder))
+	for i, kind := range installOrder {
+		rank[kind] = i
+	}
+	kindRank := func(kind string) int {
+		if r, ok := rank[kind]; ok {
+			return r
+		}
+		return len(installOrder)
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return kindRank(objects[i].obj.GetKind()) < kindRank(objects[j].obj.GetKind())
+	})
+}
+
+// ApplyManifests 把多文档YAML中的所有对象按依赖顺序使用server-side apply写入集群
+// namespace 为没有指定命名空间的对象使用的命名空间
+// continueOnError 为false时遇到第一个错误就停止，剩余的对象标记为skipped
+// 返回每个对象的处理结果，以及成功、失败、跳过的数量
+func (c *Client) ApplyManifests(ctx context.Context, namespace, manifests string, continueOnError bool, opts ApplyOptions) (map[string]interface{}, error) {
+	objects, err := splitManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no object found in manifests")
+	}
+	sortByInstallOrder(objects)
+
+	results := make([]map[string]interface{}, 0, len(objects))
+	succeeded, failed, skipped := 0, 0, 0
+	stopped := false
+	for _, item := range objects {
+		obj := item.obj
+		result := map[string]interface{}{
+			"document":  item.index,
+			"kind":      obj.GetKind(),
+			"name":      obj.GetName(),
+			"namespace": obj.GetNamespace(),
+		}
+		if stopped {
+			result["status"] = "skipped"
+			results = append(results, result)
+			skipped++
+			continue
+		}
+
+		objNamespace := obj.GetNamespace()
+		if objNamespace == "" {
+			objNamespace = namespace
+		}
+		applied, err := c.applyObject(ctx, objNamespace, "", obj, opts)
+		if err == nil && !opts.DryRun && obj.GetKind() == "CustomResourceDefinition" {
+			err = c.waitForCRDEstablished(ctx, obj.GetName())
+		}
+		if err != nil {
+			result["status"] = "failed"
+			result["error"] = err.Error()
+			results = append(results, result)
+			failed++
+			if !continueOnError {
+				stopped = true
+			}
+			continue
+		}
+
+		result["status"] = "applied"
+		result["namespace"] = applied["namespace"]
+		result["operation"] = applied["operation"]
+		result["diff"] = applied["diff"]
+		result["resourceVersion"] = applied["resourceVersion"]
+		if warning, ok := applied["warning"]; ok {
+			result["warning"] = warning
+		}
+		results = append(results, result)
+		succeeded++
+	}
+
+	return map[string]interface{}{
+		"dryRun":    opts.DryRun,
+		"succeeded": succeeded,
+		"failed":    failed,
+		"skipped":   skipped,
+		"results":   results,
+	}, nil
+}
+
+// waitForCRDEstablished 等待CRD的Established条件为True，这样后面的自定义资源才能被解析和创建
+func (c *Client) waitForCRDEstablished(ctx context.Context, name string) error {
+	gvr, err := c.getCachedGVR("CustomResourceDefinition")
+	if err != nil {
+		return err
+	}
+	err = wait.PollUntilContextTimeout(ctx, time.Second, crdEstablishTimeout, true, func(ctx context.Context) (bool, error) {
+		crd, err := c.dynamicClient.Resource(*gvr).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+		for _, condition := range conditions {
+			cond, ok := condition.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if cond["type"] == "Established" && cond["status"] == "True" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("CustomResourceDefinition %s was not established: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package k8s
+
+import "testing"
+
+func TestSplitManifestsIndexesEveryDocument(t *testing.T) {
+	manifests := `# only a comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+# another comment
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: second
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: third
+`
+	objects, err := splitManifests(manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		index int
+		name  string
+	}{{1, "first"}, {3, "second"}, {3, "third"}}
+	if len(objects) != len(want) {
+		t.Fatalf("splitManifests() returned %d objects, want %d", len(objects), len(want))
+	}
+	for i, w := range want {
+		if objects[i].index != w.index || objects[i].obj.GetName() != w.name {
+			t.Errorf("object %d = index %d name %s, want index %d name %s", i, objects[i].index, objects[i].obj.GetName(), w.index, w.name)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// 静态pod在API Server中的镜像对象带有这个注解，它们不能被驱逐
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// 被PodDisruptionBudget拒绝后重试驱逐的间隔
+const evictionRetryInterval = 5 * time.Second
+
+// DrainOptions 控制节点排空的行为
+type DrainOptions struct {
+	// DeleteEmptyDirData 为true时允许驱逐使用emptyDir的pod，这些数据会随pod一起丢失
+	DeleteEmptyDirData bool
+	// Force 为true时允许驱逐没有控制器管理的pod，这些pod被驱逐后不会被重建
+	Force bool
+	// GracePeriodSeconds 小于0时使用pod自身的terminationGracePeriodSeconds
+	GracePeriodSeconds int64
+	// Timeout 是等待所有pod被驱逐的最长时间，PDB不允许中断时会在这段时间内不断重试
+	Timeout time.Duration
+	// DryRun 为true时不封锁节点也不驱逐，只返回将要驱逐和跳过的pod
+	DryRun bool
+}
+
+// DrainProgress 在每个pod驱逐完成（或失败）时被调用
+type DrainProgress func(done, total int, message string)
+
+// CordonNode 修改节点的spec.unschedulable，unschedulable为false时表示解除封锁
+func (c *Client) CordonNode(ctx context.Context, name string, unschedulable bool) (map[string]interface{}, error) {
+	node, err := c.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	result := map[string]interface{}{
+		"node":          name,
+		"unschedulable": unschedulable,
+		"changed":       node.Spec.Unschedulable != unschedulable,
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return result, nil
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	patched, err := c.Clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch node %s: %w", name, err)
+	}
+	result["resourceVersion"] = patched.ResourceVersion
+	return result, nil
+}
+
+// drainCandidate 是节点上需要被驱逐的pod
+type drainCandidate struct {
+	pod corev1.Pod
+	// blockedBy 是当前不允许中断这个pod的PDB
+	blockedBy []string
+}
+
+// DrainNode 封锁节点并通过Eviction API驱逐节点上的pod，和kubectl drain的规则一致：
+// DaemonSet管理的pod和静态pod会被跳过；使用emptyDir的pod和没有控制器管理的pod需要显式允许才会被驱逐
+// PodDisruptionBudget不允许中断时会持续重试直到超时
+func (c *Client) DrainNode(ctx context.Context, name string, opts DrainOptions, progress DrainProgress) (map[string]interface{}, error) {
+	if opts.Timeout <= 0 {
+		return nil, fmt.Errorf("drain timeout must be positive")
+	}
+	if _, err := c.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	pods, err := c.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	var candidates []drainCandidate
+	skipped := []map[string]interface{}{}
+	blocked := []map[string]interface{}{}
+	for _, pod := range pods.Items {
+		reason, ok := drainFilter(&pod, opts)
+		if reason == "" {
+			candidates = append(candidates, drainCandidate{pod: pod})
+			continue
+		}
+		entry := map[string]interface{}{"pod": pod.Namespace + "/" + pod.Name, "reason": reason}
+		if ok {
+			skipped = append(skipped, entry)
+		} else {
+			blocked = append(blocked, entry)
+		}
+	}
+	if err := c.findBlockingPDBs(ctx, candidates); err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"node":    name,
+		"dryRun":  opts.DryRun,
+		"skipped": skipped,
+	}
+	if len(blocked) > 0 {
+		result["blocked"] = blocked
+		if !opts.DryRun {
+			return nil, fmt.Errorf("cannot drain node %s, %d pods need deleteEmptyDirData or force: %v", name, len(blocked), blocked)
+		}
+	}
+	if opts.DryRun {
+		toEvict := []map[string]interface{}{}
+		for _, candidate := range candidates {
+			entry := map[string]interface{}{"pod": candidate.pod.Namespace + "/" + candidate.pod.Name}
+			if len(candidate.blockedBy) > 0 {
+				entry["disruptionBlockedBy"] = candidate.blockedBy
+			}
+			toEvict = append(toEvict, entry)
+		}
+		result["wouldEvict"] = toEvict
+		return result, nil
+	}
+
+	if _, err := c.CordonNode(ctx, name, true); err != nil {
+		return nil, err
+	}
+	result["cordoned"] = true
+
+	drainCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	evicted := []string{}
+	failed := []map[string]interface{}{}
+	done := 0
+	for _, candidate := range candidates {
+		wg.Add(1)
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+			key := pod.Namespace + "/" + pod.Name
+			err := c.evictPod(drainCtx, pod, opts.GracePeriodSeconds)
+			lock.Lock()
+			defer lock.Unlock()
+			done++
+			if err != nil {
+				failed = append(failed, map[string]interface{}{"pod": key, "error": err.Error()})
+				if progress != nil {
+					progress(done, len(candidates), fmt.Sprintf("failed to evict %s: %v", key, err))
+				}
+				return
+			}
+			evicted = append(evicted, key)
+			if progress != nil {
+				progress(done, len(candidates), fmt.Sprintf("evicted %s", key))
+			}
+		}(candidate.pod)
+	}
+	wg.Wait()
+
+	sort.Strings(evicted)
+	result["evicted"] = evicted
+	result["failed"] = failed
+	result["timedOut"] = drainCtx.Err() == context.DeadlineExceeded
+	result["drained"] = len(failed) == 0
+	return result, nil
+}
+
+// drainFilter 判断pod是否需要驱逐，返回空字符串表示需要驱逐
+// 否则返回原因，第二个返回值表示这个pod可以被安全跳过（为false时表示它阻止了排空）
+func drainFilter(pod *corev1.Pod, opts DrainOptions) (string, bool) {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return "mirror pod", true
+	}
+	// 已经结束的pod直接驱逐，不需要检查emptyDir和控制器
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return "", true
+	}
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		return "managed by DaemonSet " + controller.Name, true
+	}
+	if controller == nil && !opts.Force {
+		return "not managed by a controller, use force to evict it", false
+	}
+	if !opts.DeleteEmptyDirData {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				return fmt.Sprintf("uses emptyDir volume %s, use deleteEmptyDirData to evict it", volume.Name), false
+			}
+		}
+	}
+	return "", true
+}
+
+// findBlockingPDBs 找出当前不允许中断的PDB，dry-run时用来提示哪些pod的驱逐会被阻塞
+func (c *Client) findBlockingPDBs(ctx context.Context, candidates []drainCandidate) error {
+	pdbsByNamespace := map[string][]policyv1.PodDisruptionBudget{}
+	for i := range candidates {
+		pod := &candidates[i].pod
+		pdbs, ok := pdbsByNamespace[pod.Namespace]
+		if !ok {
+			list, err := c.Clientset.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list PodDisruptionBudgets in %s: %w", pod.Namespace, err)
+			}
+			pdbs = list.Items
+			pdbsByNamespace[pod.Namespace] = pdbs
+		}
+		for _, pdb := range pdbs {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || selector.Empty() {
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) && pdb.Status.DisruptionsAllowed == 0 {
+				candidates[i].blockedBy = append(candidates[i].blockedBy, pdb.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// evictPod 通过Eviction API驱逐pod并等待它被删除
+// PDB不允许中断时API Server返回429，此时按间隔重试直到ctx超时
+func (c *Client) evictPod(ctx context.Context, pod corev1.Pod, gracePeriodSeconds int64) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	if gracePeriodSeconds >= 0 {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}
+	}
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, evictionRetryInterval, true, func(ctx context.Context) (bool, error) {
+		err := c.Clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil, errors.IsNotFound(err):
+			return true, nil
+		case errors.IsTooManyRequests(err):
+			lastErr = err
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+	if err != nil {
+		if lastErr != nil && ctx.Err() != nil {
+			return fmt.Errorf("eviction was blocked by a PodDisruptionBudget until timeout: %w", lastErr)
+		}
+		return err
+	}
+
+	// 等待pod被删除，同名pod被重建时UID会变化
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := c.Clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, nil
+		}
+		return current.UID != pod.UID, nil
+	})
+	if err != nil {
+		return fmt.Errorf("pod was evicted but not deleted before timeout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// 客户端apply时写入的注解，内容是完整的manifest
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Projection 控制返回对象中的哪些部分，零值表示返回完整对象
+type Projection struct {
+	// Fields 是要返回的字段路径，例如 spec.replicas、status.conditions[*].type，和kubectl custom-columns类似
+	Fields []string
+	// JSONPath 是kubectl -o jsonpath格式的模板，设置后返回模板渲染出的文本
+	JSONPath string
+	// StripManaged 为true时去掉managedFields和last-applied注解
+	StripManaged bool
+}
+
+// IsFull 表示是否需要返回完整的对象（可能去掉了managedFields）
+func (p Projection) IsFull() bool {
+	return len(p.Fields) == 0 && p.JSONPath == ""
+}
+
+// Apply 对单个对象做投影，返回值是以字段路径为key的map、jsonpath渲染出的文本或者完整对象
+// obj 可能来自informer缓存，不会被修改
+func (p Projection) Apply(obj map[string]interface{}) (interface{}, error) {
+	switch {
+	case p.JSONPath != "":
+		return executeJSONPath(p.JSONPath, obj)
+	case len(p.Fields) > 0:
+		return projectFields(p.Fields, obj)
+	case p.StripManaged:
+		return stripManaged(obj), nil
+	default:
+		return obj, nil
+	}
+}
+
+// stripManaged 返回去掉managedFields和last-applied注解的对象
+// 只复制被修改的metadata和annotations，其余部分和obj共享
+func stripManaged(obj map[string]interface{}) map[string]interface{} {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	result := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		result[key] = value
+	}
+	newMetadata := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if key != "managedFields" {
+			newMetadata[key] = value
+		}
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		newAnnotations := make(map[string]interface{}, len(annotations))
+		for key, value := range annotations {
+			if key != lastAppliedAnnotation {
+				newAnnotations[key] = value
+			}
+		}
+		if len(newAnnotations) == 0 {
+			delete(newMetadata, "annotations")
+		} else {
+			newMetadata["annotations"] = newAnnotations
+		}
+	}
+	result["metadata"] = newMetadata
+	return result
+}
+
+// projectFields 取出每个字段路径的值，路径不存在时为nil，匹配到多个值（例如使用了[*]）时为列表
+func projectFields(fields []string, obj map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(field, "{"), "}")
+		if !strings.HasPrefix(path, ".") {
+			path = "." + path
+		}
+		parser := jsonpath.New(field).AllowMissingKeys(true)
+		if err := parser.Parse("{" + path + "}"); err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		results, err := parser.FindResults(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate field %q: %w", field, err)
+		}
+		var values []interface{}
+		for _, group := range results {
+			for _, value := range group {
+				values = append(values, value.Interface())
+			}
+		}
+		switch {
+		case len(values) == 0:
+			result[field] = nil
+		case len(values) == 1 && !strings.Contains(path, "[*]") && !strings.Contains(path, "..") && !strings.Contains(path, "?("):
+			result[field] = values[0]
+		default:
+			result[field] = values
+		}
+	}
+	return result, nil
+}
+
+// executeJSONPath 按kubectl -o jsonpath的规则渲染模板，模板可以省略外层的 {}
+func executeJSONPath(template string, data interface{}) (string, error) {
+	if !strings.Contains(template, "{") {
+		template = "{" + template + "}"
+	}
+	parser := jsonpath.New("jsonPath").AllowMissingKeys(true)
+	if err := parser.Parse(template); err != nil {
+		return "", fmt.Errorf("invalid jsonPath %q: %w", template, err)
+	}
+	var buf bytes.Buffer
+	if err := parser.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to evaluate jsonPath %q: %w", template, err)
+	}
+	return buf.String(), nil
+}
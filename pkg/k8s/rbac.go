@@ -0,0 +1,318 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessCheck 描述一次权限检查：Kind支持和其他工具相同的写法，也可以是 pods/log 这样的子资源，
+// 以 / 开头时表示非资源URL（例如 /healthz）。User和Groups为空时检查客户端自己的身份（服务器或者被模拟的调用方）
+type AccessCheck struct {
+	Verb      string
+	Kind      string
+	Name      string
+	Namespace string
+	User      string
+	Groups    []string
+}
+
+// accessTarget 是解析后的资源
+type accessTarget struct {
+	group          string
+	resource       string
+	subresource    string
+	nonResourceURL string
+	namespaced     bool
+}
+
+func (c *Client) resolveAccessTarget(kind string) (*accessTarget, error) {
+	if strings.HasPrefix(kind, "/") {
+		return &accessTarget{nonResourceURL: kind}, nil
+	}
+	kind, subresource, _ := strings.Cut(kind, "/")
+	if kind == "*" {
+		return &accessTarget{group: "*", resource: "*", subresource: subresource, namespaced: true}, nil
+	}
+	resource, err := c.resolveAPIResource(kind)
+	if err != nil {
+		return nil, err
+	}
+	return &accessTarget{
+		group:       resource.gvr.Group,
+		resource:    resource.gvr.Resource,
+		subresource: subresource,
+		namespaced:  resource.namespaced,
+	}, nil
+}
+
+func (t *accessTarget) String() string {
+	if t.nonResourceURL != "" {
+		return t.nonResourceURL
+	}
+	name := t.resource
+	if t.group != "" {
+		name += "." + t.group
+	}
+	if t.subresource != "" {
+		name += "/" + t.subresource
+	}
+	return name
+}
+
+// CanI 和kubectl auth can-i一样，没有指定用户时使用SelfSubjectAccessReview，否则使用SubjectAccessReview
+func (c *Client) CanI(ctx context.Context, check AccessCheck) (map[string]interface{}, error) {
+	if check.Verb == "" {
+		return nil, fmt.Errorf("verb is required")
+	}
+	target, err := c.resolveAccessTarget(check.Kind)
+	if err != nil {
+		return nil, err
+	}
+	namespace := check.Namespace
+	if !target.namespaced {
+		namespace = ""
+	}
+	var resourceAttributes *authorizationv1.ResourceAttributes
+	var nonResourceAttributes *authorizationv1.NonResourceAttributes
+	if target.nonResourceURL != "" {
+		nonResourceAttributes = &authorizationv1.NonResourceAttributes{Path: target.nonResourceURL, Verb: check.Verb}
+	} else {
+		resourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace:   namespace,
+			Verb:        check.Verb,
+			Group:       target.group,
+			Resource:    target.resource,
+			Subresource: target.subresource,
+			Name:        check.Name,
+		}
+	}
+
+	var status authorizationv1.SubjectAccessReviewStatus
+	subject := "the server's own identity"
+	if !c.identity.IsZero() {
+		subject = c.identity.String()
+	}
+	if check.User == "" && len(check.Groups) == 0 {
+		review, err := c.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes:    resourceAttributes,
+				NonResourceAttributes: nonResourceAttributes,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SelfSubjectAccessReview: %w", err)
+		}
+		status = review.Status
+	} else {
+		review, err := c.Clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes:    resourceAttributes,
+				NonResourceAttributes: nonResourceAttributes,
+				User:                  check.User,
+				Groups:                check.Groups,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SubjectAccessReview: %w", err)
+		}
+		status = review.Status
+		subject = check.User
+		if len(check.Groups) > 0 {
+			subject = strings.TrimSpace(fmt.Sprintf("%s (groups %s)", check.User, strings.Join(check.Groups, ", ")))
+		}
+	}
+
+	result := map[string]interface{}{
+		"allowed":  status.Allowed,
+		"verb":     check.Verb,
+		"resource": target.String(),
+		"subject":  subject,
+	}
+	if check.Name != "" {
+		result["name"] = check.Name
+	}
+	if namespace != "" {
+		result["namespace"] = namespace
+	}
+	if status.Denied {
+		result["denied"] = true
+	}
+	if status.Reason != "" {
+		result["reason"] = status.Reason
+	}
+	if status.EvaluationError != "" {
+		result["evaluationError"] = status.EvaluationError
+	}
+	return result, nil
+}
+
+// ruleAllows 判断一条PolicyRule是否允许对资源执行verb，规则和API Server的RBAC授权器一致：
+// * 匹配所有verb、group和资源，*/subresource 匹配所有资源的某个子资源，resourceNames为空时匹配所有名称
+func ruleAllows(rule rbacv1.PolicyRule, verb string, target *accessTarget, name string) bool {
+	if !containsOrWildcard(rule.Verbs, verb) {
+		return false
+	}
+	if target.nonResourceURL != "" {
+		for _, url := range rule.NonResourceURLs {
+			if url == "*" || url == target.nonResourceURL || (strings.HasSuffix(url, "*") && strings.HasPrefix(target.nonResourceURL, strings.TrimSuffix(url, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+	if !containsOrWildcard(rule.APIGroups, target.group) {
+		return false
+	}
+	resource := target.resource
+	if target.subresource != "" {
+		resource += "/" + target.subresource
+	}
+	matched := false
+	for _, r := range rule.Resources {
+		if r == "*" || r == resource || (target.subresource != "" && r == "*/"+target.subresource) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	if len(rule.ResourceNames) == 0 {
+		return true
+	}
+	return name != "" && containsOrWildcard(rule.ResourceNames, name)
+}
+
+func containsOrWildcard(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func rulesAllow(rules []rbacv1.PolicyRule, verb string, target *accessTarget, name string) bool {
+	for _, rule := range rules {
+		if ruleAllows(rule, verb, target, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WhoCan 遍历Role、ClusterRole和它们的绑定，列出被允许对资源执行verb的主体
+// namespace为空时检查所有命名空间的RoleBinding；只会考虑RBAC，其他授权方式（例如webhook）授予的权限不会出现在结果中
+func (c *Client) WhoCan(ctx context.Context, check AccessCheck) (map[string]interface{}, error) {
+	if check.Verb == "" {
+		return nil, fmt.Errorf("verb is required")
+	}
+	target, err := c.resolveAccessTarget(check.Kind)
+	if err != nil {
+		return nil, err
+	}
+	rbac := c.Clientset.RbacV1()
+	clusterRoles, err := rbac.ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusterroles: %w", err)
+	}
+	clusterRoleAllows := map[string]bool{}
+	for _, role := range clusterRoles.Items {
+		clusterRoleAllows[role.Name] = rulesAllow(role.Rules, check.Verb, target, check.Name)
+	}
+
+	type grant struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+		Binding   string `json:"binding"`
+		Role      string `json:"role"`
+		// Scope 是权限生效的范围：cluster或者命名空间的名称
+		Scope string `json:"scope"`
+	}
+	var grants []grant
+	addSubjects := func(subjects []rbacv1.Subject, binding, role, scope string) {
+		for _, subject := range subjects {
+			grants = append(grants, grant{
+				Kind:      subject.Kind,
+				Name:      subject.Name,
+				Namespace: subject.Namespace,
+				Binding:   binding,
+				Role:      role,
+				Scope:     scope,
+			})
+		}
+	}
+
+	clusterBindings, err := rbac.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusterrolebindings: %w", err)
+	}
+	for _, binding := range clusterBindings.Items {
+		if binding.RoleRef.Kind == "ClusterRole" && clusterRoleAllows[binding.RoleRef.Name] {
+			addSubjects(binding.Subjects, "ClusterRoleBinding/"+binding.Name, "ClusterRole/"+binding.RoleRef.Name, "cluster")
+		}
+	}
+
+	// 集群级别的资源和非资源URL只能通过ClusterRoleBinding授权
+	if target.nonResourceURL == "" && target.namespaced {
+		namespace := check.Namespace
+		roles, err := rbac.Roles(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list roles: %w", err)
+		}
+		roleAllows := map[string]bool{}
+		for _, role := range roles.Items {
+			roleAllows[role.Namespace+"/"+role.Name] = rulesAllow(role.Rules, check.Verb, target, check.Name)
+		}
+		bindings, err := rbac.RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rolebindings: %w", err)
+		}
+		for _, binding := range bindings.Items {
+			allowed := false
+			switch binding.RoleRef.Kind {
+			case "ClusterRole":
+				allowed = clusterRoleAllows[binding.RoleRef.Name]
+			case "Role":
+				allowed = roleAllows[binding.Namespace+"/"+binding.RoleRef.Name]
+			}
+			if allowed {
+				addSubjects(binding.Subjects, "RoleBinding/"+binding.Namespace+"/"+binding.Name, binding.RoleRef.Kind+"/"+binding.RoleRef.Name, binding.Namespace)
+			}
+		}
+	}
+
+	sort.SliceStable(grants, func(i, j int) bool {
+		if grants[i].Kind != grants[j].Kind {
+			return grants[i].Kind < grants[j].Kind
+		}
+		left, right := grants[i].Namespace+"/"+grants[i].Name, grants[j].Namespace+"/"+grants[j].Name
+		if left != right {
+			return left < right
+		}
+		return grants[i].Binding < grants[j].Binding
+	})
+	result := map[string]interface{}{
+		"verb":     check.Verb,
+		"resource": target.String(),
+		"subjects": grants,
+		"note":     "only RBAC is evaluated, permissions granted by other authorizers are not listed",
+	}
+	if grants == nil {
+		result["subjects"] = []grant{}
+	}
+	if check.Name != "" {
+		result["name"] = check.Name
+	}
+	if check.Namespace != "" && target.namespaced {
+		result["namespace"] = check.Namespace
+	}
+	return result, nil
+}
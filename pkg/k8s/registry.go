@@ -0,0 +1,296 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// 单集群模式（KUBERNETES_SERVER 或 in-cluster）下集群的名称
+const DefaultClusterName = "default"
+
+// 懒启动时等待informer缓存同步的最长时间，超时后未同步的资源会直接走API Server
+const cacheSyncTimeout = 60 * time.Second
+
+// 探测集群是否可达时使用的超时时间
+const reachableTimeout = 5 * time.Second
+
+// clusterEntry 保存一个集群的rest config，以及懒加载出来的Client
+// ready 在client的informer缓存同步完成（或超时）后关闭；impersonated 缓存按身份模拟的客户端，key是Identity.cacheKey()
+type clusterEntry struct {
+	config       *rest.Config
+	client       *Client
+	ready        chan struct{}
+	impersonated map[string]*Client
+	lock         sync.Mutex
+}
+
+// Registry 按集群名称（kubeconfig中的context名）管理多个集群的Client
+// 每个集群都有自己的informer factory和GVR缓存，在第一次被使用时才创建并启动
+type Registry struct {
+	ctx             context.Context
+	defaultCluster  string
+	defaultIdentity Identity
+	names           []string
+	clusters        map[string]*clusterEntry
+}
+
+// NewRegistry 加载所有可用的集群配置，但并不连接任何集群
+// ctx 控制所有informer的生命周期；defaultCluster 为空时使用kubeconfig的current-context
+// 配置的加载顺序和 BuildRestConfig 保持一致：
+// - KUBECONFIG_DATA 环境变量中的kubeconfig，每个context都是一个集群
+// - KUBERNETES_SERVER/KUBERNETES_TOKEN 或 in-cluster config，只有一个名为default的集群
+// - kubeconfig文件（kubeconfigPath、KUBECONFIG 或 ~/.kube/config），每个context都是一个集群
+func NewRegistry(ctx context.Context, kubeconfigPath, defaultCluster string) (*Registry, error) {
+	configs, current, err := loadClusterConfigs(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no cluster found in kubeconfig")
+	}
+
+	r := &Registry{
+		ctx:      ctx,
+		clusters: make(map[string]*clusterEntry, len(configs)),
+	}
+	for name, config := range configs {
+		r.names = append(r.names, name)
+		r.clusters[name] = &clusterEntry{config: config}
+	}
+	sort.Strings(r.names)
+
+	switch {
+	case defaultCluster != "":
+		if _, ok := r.clusters[defaultCluster]; !ok {
+			return nil, fmt.Errorf("default cluster %q not found, available clusters: %s", defaultCluster, strings.Join(r.names, ", "))
+		}
+		r.defaultCluster = defaultCluster
+	case current != "":
+		r.defaultCluster = current
+	default:
+		r.defaultCluster = r.names[0]
+	}
+	return r, nil
+}
+
+// loadClusterConfigs 返回 集群名 -> rest config，以及当前默认的集群名
+func loadClusterConfigs(kubeconfigPath string) (map[string]*rest.Config, string, error) {
+	if kubeconfigData := os.Getenv("KUBECONFIG_DATA"); kubeconfigData != "" {
+		raw, err := clientcmd.Load([]byte(kubeconfigData))
+		if err != nil {
+			return nil, "", fmt.Errorf("构建config失败 %w", err)
+		}
+		return configsFromKubeconfig(raw)
+	}
+
+	// 这两种方式都只能描述一个集群，直接复用 BuildRestConfig
+	if os.Getenv("KUBERNETES_SERVER") != "" || isInCluster() {
+		config, err := BuildRestConfig(kubeconfigPath)
+		if err != nil {
+			return nil, "", err
+		}
+		return map[string]*rest.Config{DefaultClusterName: config}, DefaultClusterName, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		rules.ExplicitPath = kubeconfigPath
+	}
+	raw, err := rules.Load()
+	if err != nil {
+		return nil, "", fmt.Errorf("构建config失败 %w", err)
+	}
+	return configsFromKubeconfig(raw)
+}
+
+func isInCluster() bool {
+	_, err := rest.InClusterConfig()
+	return err == nil
+}
+
+// configsFromKubeconfig 为kubeconfig中的每个context构建一个rest config
+func configsFromKubeconfig(raw *clientcmdapi.Config) (map[string]*rest.Config, string, error) {
+	configs := make(map[string]*rest.Config, len(raw.Contexts))
+	for name := range raw.Contexts {
+		config, err := clientcmd.NewNonInteractiveClientConfig(*raw, name, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
+			return nil, "", fmt.Errorf("构建context %s 的config失败 %w", name, err)
+		}
+		configs[name] = config
+	}
+	current := raw.CurrentContext
+	if _, ok := configs[current]; !ok {
+		current = ""
+	}
+	return configs, current, nil
+}
+
+// DefaultCluster 返回未指定cluster参数时使用的集群名
+func (r *Registry) DefaultCluster() string {
+	return r.defaultCluster
+}
+
+// Clusters 返回所有已配置的集群名，按名称排序
+func (r *Registry) Clusters() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Client 返回指定集群的Client，name为空时返回默认集群
+// 第一次调用时会创建Client并在后台启动informer，所有调用都等待缓存同步（最多cacheSyncTimeout），等待时不持有锁
+func (r *Registry) Client(name string) (*Client, error) {
+	if name == "" {
+		name = r.defaultCluster
+	}
+	entry, ok := r.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found, available clusters: %s", name, strings.Join(r.names, ", "))
+	}
+
+	entry.lock.Lock()
+	if entry.client == nil {
+		client, err := NewClientForConfig(entry.config)
+		if err != nil {
+			entry.lock.Unlock()
+			return nil, fmt.Errorf("failed to create client for cluster %s: %w", name, err)
+		}
+		entry.client = client
+		entry.ready = make(chan struct{})
+		go r.startInformers(name, client, entry.ready)
+	}
+	client, ready := entry.client, entry.ready
+	entry.lock.Unlock()
+
+	select {
+	case <-ready:
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+	return client, nil
+}
+
+// startInformers 启动informer并等待缓存同步，完成后关闭ready
+// stdio模式下stdout是JSON-RPC的通道，日志只能写到stderr
+func (r *Registry) startInformers(name string, client *Client, ready chan struct{}) {
+	defer close(ready)
+	fmt.Fprintf(os.Stderr, "Starting Informers for cluster %s...\n", name)
+	client.StartInformers(r.ctx)
+	syncCtx, cancel := context.WithTimeout(r.ctx, cacheSyncTimeout)
+	defer cancel()
+	if !client.WaitForCacheSync(syncCtx) {
+		fmt.Fprintf(os.Stderr, "Warning: Informer caches for cluster %s failed to sync\n", name)
+	} else {
+		fmt.Fprintf(os.Stderr, "Informer caches for cluster %s synced successfully\n", name)
+	}
+}
+
+// SetDefaultIdentity 设置context中没有调用方身份时模拟的身份，为空时使用服务器自己的身份
+func (r *Registry) SetDefaultIdentity(identity Identity) {
+	r.defaultIdentity = identity
+}
+
+// DefaultIdentity 返回SetDefaultIdentity设置的身份
+func (r *Registry) DefaultIdentity() Identity {
+	return r.defaultIdentity
+}
+
+// ClientFor 返回以ctx中的调用方身份（没有时使用默认身份）访问指定集群的Client
+// 没有任何身份时和Client相同；否则返回按集群和身份缓存的模拟客户端
+func (r *Registry) ClientFor(ctx context.Context, name string) (*Client, error) {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		identity = r.defaultIdentity
+	}
+	base, err := r.Client(name)
+	if err != nil || identity.IsZero() {
+		return base, err
+	}
+	if name == "" {
+		name = r.defaultCluster
+	}
+	entry := r.clusters[name]
+	key := identity.cacheKey()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if client, ok := entry.impersonated[key]; ok {
+		return client, nil
+	}
+	client, err := newImpersonatedClient(base, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s in cluster %s: %w", identity, name, err)
+	}
+	if entry.impersonated == nil {
+		entry.impersonated = map[string]*Client{}
+	}
+	entry.impersonated[key] = client
+	return client, nil
+}
+
+// ListClusters 列出所有已配置的集群，以及它们是否可达、informer的状态
+// informer状态为 stopped（还未使用过）、starting（正在等待缓存同步）或 started
+func (r *Registry) ListClusters() []map[string]interface{} {
+	result := make([]map[string]interface{}, len(r.names))
+	var wg sync.WaitGroup
+	for i, name := range r.names {
+		entry := r.clusters[name]
+		informers := "stopped"
+		entry.lock.Lock()
+		if entry.client != nil {
+			informers = "started"
+			select {
+			case <-entry.ready:
+			default:
+				informers = "starting"
+			}
+		}
+		entry.lock.Unlock()
+
+		info := map[string]interface{}{
+			"name":      name,
+			"server":    entry.config.Host,
+			"default":   name == r.defaultCluster,
+			"informers": informers,
+			"reachable": false,
+		}
+		result[i] = info
+
+		wg.Add(1)
+		go func(config *rest.Config, info map[string]interface{}) {
+			defer wg.Done()
+			version, err := serverVersion(config)
+			if err != nil {
+				info["error"] = err.Error()
+				return
+			}
+			info["reachable"] = true
+			info["version"] = version
+		}(entry.config, info)
+	}
+	wg.Wait()
+	return result
+}
+
+// serverVersion 通过discovery探测集群是否可达，返回集群的版本号
+func serverVersion(config *rest.Config) (string, error) {
+	probeConfig := rest.CopyConfig(config)
+	probeConfig.Timeout = reachableTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(probeConfig)
+	if err != nil {
+		return "", err
+	}
+	info, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return info.GitVersion, nil
+}
@@ -0,0 +1,230 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// apiResource 是discovery返回的一种资源
+// key 是它在缓存和Informer中使用的名称：一般就是Kind，多个group中存在同名Kind时，
+// 先注册的（core group优先）使用Kind，其余的使用 Kind.group，例如 Event.events.k8s.io
+type apiResource struct {
+	key        string
+	gvr        schema.GroupVersionResource
+	kind       string
+	singular   string
+	shortNames []string
+	namespaced bool
+	verbs      []string
+}
+
+// registerAPIResources 把discovery的结果加入resolver，已经注册过的资源保持原来的key
+func (c *Client) registerAPIResources(resourceLists []*metav1.APIResourceList) []*apiResource {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	var added []*apiResource
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			// 跳过pods/log这样的子资源
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			gvr := gv.WithResource(resource.Name)
+			if c.findRegisteredGVR(gvr) != nil {
+				continue
+			}
+			key := resource.Kind
+			if existing, taken := c.apiResources[key]; taken && existing.gvr.Group != gv.Group {
+				key = resource.Kind + "." + gv.Group
+			}
+			if _, taken := c.apiResources[key]; taken {
+				continue
+			}
+			r := &apiResource{
+				key:        key,
+				gvr:        gvr,
+				kind:       resource.Kind,
+				singular:   resource.SingularName,
+				shortNames: resource.ShortNames,
+				namespaced: resource.Namespaced,
+				verbs:      resource.Verbs,
+			}
+			if r.singular == "" {
+				r.singular = strings.ToLower(resource.Kind)
+			}
+			c.apiResources[key] = r
+			added = append(added, r)
+		}
+	}
+	return added
+}
+
+// findRegisteredGVR 调用方需要持有cacheLock
+func (c *Client) findRegisteredGVR(gvr schema.GroupVersionResource) *apiResource {
+	for _, r := range c.apiResources {
+		if r.gvr.Group == gvr.Group && r.gvr.Resource == gvr.Resource {
+			return r
+		}
+	}
+	return nil
+}
+
+// matchesName 判断name是否是资源的Kind、复数、单数或简称之一，大小写不敏感
+func (r *apiResource) matchesName(name string) bool {
+	if strings.EqualFold(name, r.kind) || strings.EqualFold(name, r.gvr.Resource) || strings.EqualFold(name, r.singular) {
+		return true
+	}
+	for _, shortName := range r.shortNames {
+		if strings.EqualFold(name, shortName) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 判断输入是否指向这个资源，支持以下写法：
+// pod、pods、po、Pod（不带group），deployments.apps、deploy.apps（名称.group），Deployment.v1.apps（Kind.version.group）
+// core group 的资源可以写成 pods.v1 或 Pod.v1
+func (r *apiResource) matches(input string) bool {
+	if r.matchesName(input) {
+		return true
+	}
+	name, qualifier, found := strings.Cut(input, ".")
+	if !found || !r.matchesName(name) {
+		return false
+	}
+	if strings.EqualFold(qualifier, r.gvr.Group) {
+		return true
+	}
+	version, group, _ := strings.Cut(qualifier, ".")
+	return version == r.gvr.Version && strings.EqualFold(group, r.gvr.Group)
+}
+
+// lookupAPIResource 在已注册的资源中查找，没有找到时返回nil
+// 匹配到多个group时优先使用key和输入完全一致的，其次是core group，否则返回列出候选group的错误
+func (c *Client) lookupAPIResource(input string) (*apiResource, error) {
+	c.cacheLock.RLock()
+	defer c.cacheLock.RUnlock()
+	if r, ok := c.apiResources[input]; ok {
+		return r, nil
+	}
+	var candidates []*apiResource
+	for _, r := range c.apiResources {
+		if r.matches(input) {
+			candidates = append(candidates, r)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return candidates[0], nil
+	}
+	for _, r := range candidates {
+		if r.gvr.Group == "" {
+			return r, nil
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].gvr.Group < candidates[j].gvr.Group
+	})
+	options := make([]string, 0, len(candidates))
+	for _, r := range candidates {
+		options = append(options, fmt.Sprintf("%s.%s (%s)", r.gvr.Resource, r.gvr.Group, r.gvr.GroupVersion().String()))
+	}
+	return nil, fmt.Errorf("kind %s is ambiguous, qualify it with a group, one of: %s", input, strings.Join(options, ", "))
+}
+
+// resolveAPIResource 把用户输入的kind解析为资源，找不到时重新做一次discovery（新安装的CRD）
+func (c *Client) resolveAPIResource(input string) (*apiResource, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, fmt.Errorf("kind is required")
+	}
+	r, err := c.lookupAPIResource(input)
+	if err != nil || r != nil {
+		return r, err
+	}
+	resourceLists, err := c.discoveryClient.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to retrieve api resource:%w", err)
+	}
+	c.registerAPIResources(resourceLists)
+	r, err = c.lookupAPIResource(input)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("resource type %s not found, use getAPIResources to list the available kinds", input)
+	}
+	return r, nil
+}
+
+// resolveGVK 按manifest中的apiVersion和kind解析资源，避免同名Kind解析到其他group
+func (c *Client) resolveGVK(apiVersion, kind string) (*apiResource, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apiVersion %s: %w", apiVersion, err)
+	}
+	for i := 0; i < 2; i++ {
+		c.cacheLock.RLock()
+		var found *apiResource
+		for _, r := range c.apiResources {
+			if r.kind == kind && r.gvr.Group == gv.Group {
+				found = r
+				break
+			}
+		}
+		c.cacheLock.RUnlock()
+		if found != nil {
+			// 服务器的首选版本可能和manifest不同，写入时使用manifest中的版本
+			resolved := *found
+			resolved.gvr.Version = gv.Version
+			return &resolved, nil
+		}
+		if i == 0 {
+			resourceLists, err := c.discoveryClient.ServerPreferredResources()
+			if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+				return nil, fmt.Errorf("failed to retrieve api resource:%w", err)
+			}
+			c.registerAPIResources(resourceLists)
+		}
+	}
+	return nil, fmt.Errorf("resource type %s in %s not found", kind, apiVersion)
+}
+
+// ResolveKind 返回输入的kind对应的规范名称（缓存中使用的key），供上层在日志和策略中使用
+func (c *Client) ResolveKind(kind string) (string, error) {
+	r, err := c.resolveAPIResource(kind)
+	if err != nil {
+		return "", err
+	}
+	return r.key, nil
+}
+
+// ResolveKindScope 和ResolveKind相同，同时返回资源是否属于命名空间
+func (c *Client) ResolveKindScope(kind string) (string, bool, error) {
+	r, err := c.resolveAPIResource(kind)
+	if err != nil {
+		return "", false, err
+	}
+	return r.key, r.namespaced, nil
+}
+
+// resourceKey 把kind转换为缓存使用的key，无法解析时原样返回
+func (c *Client) resourceKey(kind string) string {
+	r, err := c.lookupAPIResource(kind)
+	if err != nil || r == nil {
+		return kind
+	}
+	return r.key
+}
@@ -0,0 +1,494 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 默认统计的时间窗口和每个窗口的采样点数量
+const (
+	DefaultRightsizingWindow = 7 * 24 * time.Hour
+	rightsizingPoints        = 500
+)
+
+// 建议值在观测值上增加的余量
+const (
+	requestHeadroom = 1.2
+	limitHeadroom   = 1.5
+)
+
+// 判断过度分配时忽略的差值，避免对很小的容器给出建议
+const (
+	minCPUSlackMilli   = 50
+	minMemorySlackByte = 64 * 1024 * 1024
+)
+
+// 容器状态
+const (
+	RightsizingOverProvisioned  = "over-provisioned"
+	RightsizingUnderProvisioned = "under-provisioned"
+	RightsizingOK               = "ok"
+	RightsizingNoData           = "no-data"
+)
+
+// UsageHistory 是历史用量的来源，*prometheus.Client 满足这个接口
+type UsageHistory interface {
+	QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) (map[string]interface{}, error)
+}
+
+// RightsizingOptions 中Kind和Name为空时检查命名空间下所有的Deployment、StatefulSet和DaemonSet
+// History为nil时只使用metrics-server当前的用量（各副本的快照）
+type RightsizingOptions struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Window    time.Duration
+	History   UsageHistory
+}
+
+// UsageStats 是一种资源的用量分布，CPU单位为m，内存单位为Mi
+type UsageStats struct {
+	P50     string `json:"p50"`
+	P95     string `json:"p95"`
+	Max     string `json:"max"`
+	Samples int    `json:"samples"`
+}
+
+// ContainerRightsizing 是一个容器的配置、用量和建议值
+type ContainerRightsizing struct {
+	Name      string            `json:"name"`
+	Requests  map[string]string `json:"requests"`
+	Limits    map[string]string `json:"limits"`
+	CPU       *UsageStats       `json:"cpu,omitempty"`
+	Memory    *UsageStats       `json:"memory,omitempty"`
+	Status    string            `json:"status"`
+	Findings  []string          `json:"findings,omitempty"`
+	Suggested map[string]string `json:"suggested,omitempty"`
+}
+
+// WorkloadRightsizing 是一个工作负载所有容器的结果
+type WorkloadRightsizing struct {
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Replicas   int32                  `json:"replicas"`
+	Containers []ContainerRightsizing `json:"containers"`
+}
+
+// RightsizingReport 是rightsizingReport的结果，Savings是按建议值调整requests后所有副本可以释放的资源
+type RightsizingReport struct {
+	Namespace string                `json:"namespace"`
+	Source    string                `json:"source"`
+	Window    string                `json:"window,omitempty"`
+	Workloads []WorkloadRightsizing `json:"workloads"`
+	Savings   map[string]string     `json:"savings"`
+	Warnings  []string              `json:"warnings,omitempty"`
+}
+
+// rightsizingWorkload 是要检查的工作负载
+type rightsizingWorkload struct {
+	kind     string
+	name     string
+	replicas int32
+	selector *metav1.LabelSelector
+	template corev1.PodTemplateSpec
+}
+
+// containerSamples 是一个容器的用量样本，CPU单位为m，内存单位为字节
+type containerSamples struct {
+	cpu    []float64
+	memory []float64
+}
+
+// RightsizingReport 比较工作负载中容器的requests、limits和观测到的p50、p95、max用量，
+// 标记过度分配和分配不足的容器并给出建议值
+func (c *Client) RightsizingReport(ctx context.Context, opts RightsizingOptions) (*RightsizingReport, error) {
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("namespace is required")
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultRightsizingWindow
+	}
+	workloads, err := c.rightsizingWorkloads(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &RightsizingReport{
+		Namespace: opts.Namespace,
+		Source:    "metrics-server",
+		Workloads: []WorkloadRightsizing{},
+	}
+	if opts.History != nil {
+		report.Source = "prometheus"
+		report.Window = opts.Window.String()
+	} else {
+		report.Warnings = append(report.Warnings, "Prometheus is not enabled for this cluster, the report uses a single metrics-server snapshot of the current replicas and is much less reliable than usage history")
+	}
+
+	var cpuSavings, memorySavings float64
+	for _, workload := range workloads {
+		samples, warning := c.workloadSamples(ctx, opts, workload)
+		if warning != "" {
+			report.Warnings = append(report.Warnings, warning)
+		}
+		result := WorkloadRightsizing{
+			Kind:       workload.kind,
+			Name:       workload.name,
+			Replicas:   workload.replicas,
+			Containers: []ContainerRightsizing{},
+		}
+		for _, container := range workload.template.Spec.Containers {
+			entry, cpuSaved, memorySaved := rightsizeContainer(container, samples[container.Name])
+			cpuSavings += cpuSaved * float64(workload.replicas)
+			memorySavings += memorySaved * float64(workload.replicas)
+			result.Containers = append(result.Containers, entry)
+		}
+		report.Workloads = append(report.Workloads, result)
+	}
+	report.Savings = map[string]string{
+		"cpuRequests":    formatMilliCPU(int64(cpuSavings)),
+		"memoryRequests": formatMemory(int64(memorySavings)),
+	}
+	return report, nil
+}
+
+// rightsizingWorkloads 列出要检查的工作负载
+func (c *Client) rightsizingWorkloads(ctx context.Context, opts RightsizingOptions) ([]rightsizingWorkload, error) {
+	kind := ""
+	if opts.Kind != "" {
+		kind = c.canonicalKind(opts.Kind)
+		if kind != "Deployment" && kind != "StatefulSet" && kind != "DaemonSet" {
+			return nil, fmt.Errorf("rightsizing supports Deployment, StatefulSet and DaemonSet, got %s", opts.Kind)
+		}
+	}
+	matches := func(k, name string) bool {
+		return (kind == "" || kind == k) && (opts.Name == "" || opts.Name == name)
+	}
+	apps := c.Clientset.AppsV1()
+	var workloads []rightsizingWorkload
+	if kind == "" || kind == "Deployment" {
+		list, err := apps.Deployments(opts.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		for _, d := range list.Items {
+			if matches("Deployment", d.Name) {
+				workloads = append(workloads, rightsizingWorkload{
+					kind: "Deployment", name: d.Name, replicas: replicasOf(d.Spec.Replicas), selector: d.Spec.Selector, template: d.Spec.Template,
+				})
+			}
+		}
+	}
+	if kind == "" || kind == "StatefulSet" {
+		list, err := apps.StatefulSets(opts.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+		}
+		for _, s := range list.Items {
+			if matches("StatefulSet", s.Name) {
+				workloads = append(workloads, rightsizingWorkload{
+					kind: "StatefulSet", name: s.Name, replicas: replicasOf(s.Spec.Replicas), selector: s.Spec.Selector, template: s.Spec.Template,
+				})
+			}
+		}
+	}
+	if kind == "" || kind == "DaemonSet" {
+		list, err := apps.DaemonSets(opts.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+		}
+		for _, ds := range list.Items {
+			if matches("DaemonSet", ds.Name) {
+				workloads = append(workloads, rightsizingWorkload{
+					kind: "DaemonSet", name: ds.Name, replicas: ds.Status.DesiredNumberScheduled, selector: ds.Spec.Selector, template: ds.Spec.Template,
+				})
+			}
+		}
+	}
+	if opts.Name != "" && len(workloads) == 0 {
+		return nil, fmt.Errorf("workload %s not found in namespace %s", opts.Name, opts.Namespace)
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if workloads[i].kind != workloads[j].kind {
+			return workloads[i].kind < workloads[j].kind
+		}
+		return workloads[i].name < workloads[j].name
+	})
+	return workloads, nil
+}
+
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// workloadSamples 返回每个容器的用量样本，优先使用Prometheus的历史数据，失败时退回metrics-server
+func (c *Client) workloadSamples(ctx context.Context, opts RightsizingOptions, workload rightsizingWorkload) (map[string]*containerSamples, string) {
+	if opts.History != nil {
+		samples, err := c.historySamples(ctx, opts, workload)
+		if err == nil {
+			return samples, ""
+		}
+		current, snapshotErr := c.snapshotSamples(ctx, opts.Namespace, workload)
+		if snapshotErr != nil {
+			return nil, fmt.Sprintf("%s %s: %v; %v", workload.kind, workload.name, err, snapshotErr)
+		}
+		return current, fmt.Sprintf("%s %s: %v, using the current metrics-server usage instead", workload.kind, workload.name, err)
+	}
+	samples, err := c.snapshotSamples(ctx, opts.Namespace, workload)
+	if err != nil {
+		return nil, fmt.Sprintf("%s %s: %v", workload.kind, workload.name, err)
+	}
+	return samples, ""
+}
+
+// historySamples 通过Prometheus查询窗口内每个Pod每个容器的CPU和内存用量
+// 只查询spec.selector当前选中的Pod：按名称前缀匹配时，Deployment web 也会匹配到 web-api 的Pod
+func (c *Client) historySamples(ctx context.Context, opts RightsizingOptions, workload rightsizingWorkload) (map[string]*containerSamples, error) {
+	podPattern, err := c.workloadPodPattern(ctx, opts.Namespace, workload)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+	start := end.Add(-opts.Window)
+	step := (opts.Window / rightsizingPoints).Truncate(time.Minute)
+	if step < time.Minute {
+		step = time.Minute
+	}
+	matcher := fmt.Sprintf(`namespace=%q,pod=~%q,container!="",container!="POD"`, opts.Namespace, podPattern)
+	cpuQuery := fmt.Sprintf("sum by (pod, container) (rate(container_cpu_usage_seconds_total{%s}[5m]))", matcher)
+	memoryQuery := fmt.Sprintf("sum by (pod, container) (container_memory_working_set_bytes{%s})", matcher)
+
+	samples := map[string]*containerSamples{}
+	add := func(query string, scale float64, cpu bool) error {
+		result, err := opts.History.QueryRange(ctx, query, start, end, step)
+		if err != nil {
+			return err
+		}
+		series, _ := result["result"].([]map[string]interface{})
+		for _, s := range series {
+			metric, _ := s["metric"].(map[string]string)
+			container := metric["container"]
+			values, _ := s["values"].([]map[string]interface{})
+			if samples[container] == nil {
+				samples[container] = &containerSamples{}
+			}
+			for _, v := range values {
+				text, _ := v["value"].(string)
+				value, err := strconv.ParseFloat(text, 64)
+				if err != nil || math.IsNaN(value) {
+					continue
+				}
+				if cpu {
+					samples[container].cpu = append(samples[container].cpu, value*scale)
+				} else {
+					samples[container].memory = append(samples[container].memory, value*scale)
+				}
+			}
+		}
+		return nil
+	}
+	if err := add(cpuQuery, 1000, true); err != nil {
+		return nil, err
+	}
+	if err := add(memoryQuery, 1, false); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("prometheus has no cAdvisor samples for the pods in the last %s", opts.Window)
+	}
+	return samples, nil
+}
+
+// workloadPodPattern 返回只匹配工作负载当前Pod名称的正则，Prometheus的正则总是匹配整个值
+func (c *Client) workloadPodPattern(ctx context.Context, namespace string, workload rightsizingWorkload) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(workload.selector)
+	if err != nil {
+		return "", fmt.Errorf("invalid selector: %w", err)
+	}
+	if selector.Empty() {
+		return "", fmt.Errorf("empty selector")
+	}
+	pods, err := c.selectorPods(ctx, namespace, selector)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pods match selector %q", selector.String())
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, regexp.QuoteMeta(pod.Name))
+	}
+	return strings.Join(names, "|"), nil
+}
+
+// snapshotSamples 使用metrics-server中各副本当前的用量作为样本
+func (c *Client) snapshotSamples(ctx context.Context, namespace string, workload rightsizingWorkload) (map[string]*containerSamples, error) {
+	selector, err := metav1.LabelSelectorAsSelector(workload.selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	list, err := c.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics, is metrics-server installed?: %w", err)
+	}
+	samples := map[string]*containerSamples{}
+	for _, pod := range list.Items {
+		for _, container := range pod.Containers {
+			if samples[container.Name] == nil {
+				samples[container.Name] = &containerSamples{}
+			}
+			samples[container.Name].cpu = append(samples[container.Name].cpu, float64(container.Usage.Cpu().MilliValue()))
+			samples[container.Name].memory = append(samples[container.Name].memory, float64(container.Usage.Memory().Value()))
+		}
+	}
+	return samples, nil
+}
+
+// percentile 使用nearest-rank方法，values需要已经排序
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return values[rank]
+}
+
+type usageSummary struct {
+	p50, p95, max float64
+	samples       int
+}
+
+func summarize(values []float64) usageSummary {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return usageSummary{
+		p50:     percentile(sorted, 50),
+		p95:     percentile(sorted, 95),
+		max:     percentile(sorted, 100),
+		samples: len(sorted),
+	}
+}
+
+// roundCPU 向上取整到10m，至少10m
+func roundCPU(milli float64) int64 {
+	return int64(math.Max(10, math.Ceil(milli/10)*10))
+}
+
+// roundMemory 向上取整到8Mi，至少16Mi
+func roundMemory(bytes float64) int64 {
+	const unit = 8 * 1024 * 1024
+	return int64(math.Max(2*unit, math.Ceil(bytes/unit)*unit))
+}
+
+// rightsizeContainer 比较一个容器的配置和用量，返回结果以及按建议值调整后每个副本节省的CPU（m）和内存（字节）
+func rightsizeContainer(container corev1.Container, samples *containerSamples) (ContainerRightsizing, float64, float64) {
+	cpuRequest := container.Resources.Requests.Cpu().MilliValue()
+	memoryRequest := container.Resources.Requests.Memory().Value()
+	cpuLimit := container.Resources.Limits.Cpu().MilliValue()
+	memoryLimit := container.Resources.Limits.Memory().Value()
+	entry := ContainerRightsizing{
+		Name:     container.Name,
+		Requests: map[string]string{},
+		Limits:   map[string]string{},
+		Status:   RightsizingNoData,
+	}
+	if cpuRequest > 0 {
+		entry.Requests["cpu"] = formatMilliCPU(cpuRequest)
+	}
+	if memoryRequest > 0 {
+		entry.Requests["memory"] = formatMemory(memoryRequest)
+	}
+	if cpuLimit > 0 {
+		entry.Limits["cpu"] = formatMilliCPU(cpuLimit)
+	}
+	if memoryLimit > 0 {
+		entry.Limits["memory"] = formatMemory(memoryLimit)
+	}
+	if samples == nil || len(samples.cpu) == 0 || len(samples.memory) == 0 {
+		return entry, 0, 0
+	}
+
+	cpu := summarize(samples.cpu)
+	memory := summarize(samples.memory)
+	entry.CPU = &UsageStats{P50: formatMilliCPU(int64(cpu.p50)), P95: formatMilliCPU(int64(cpu.p95)), Max: formatMilliCPU(int64(cpu.max)), Samples: cpu.samples}
+	entry.Memory = &UsageStats{P50: formatMemory(int64(memory.p50)), P95: formatMemory(int64(memory.p95)), Max: formatMemory(int64(memory.max)), Samples: memory.samples}
+
+	// CPU按p95、内存按max给出requests，内存超过limit会被OOMKill，所以内存更保守
+	suggestedCPURequest := roundCPU(cpu.p95 * requestHeadroom)
+	suggestedMemoryRequest := roundMemory(memory.max * requestHeadroom)
+	suggestedMemoryLimit := roundMemory(math.Max(memory.max*limitHeadroom, float64(suggestedMemoryRequest)))
+	entry.Suggested = map[string]string{
+		"requests.cpu":    formatMilliCPU(suggestedCPURequest),
+		"requests.memory": formatMemory(suggestedMemoryRequest),
+		"limits.memory":   formatMemory(suggestedMemoryLimit),
+	}
+	// CPU超过limit只会被限流，没有设置CPU limit时不建议增加
+	if cpuLimit > 0 {
+		entry.Suggested["limits.cpu"] = formatMilliCPU(roundCPU(math.Max(cpu.max*limitHeadroom, float64(suggestedCPURequest))))
+	}
+
+	cpuOver, memoryOver, under := false, false, false
+	switch {
+	case cpuRequest == 0:
+		under = true
+		entry.Findings = append(entry.Findings, "no CPU request, the scheduler cannot account for the container's CPU")
+	case cpu.p95 > float64(cpuRequest):
+		under = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("p95 CPU usage %s is above the request %s", formatMilliCPU(int64(cpu.p95)), formatMilliCPU(cpuRequest)))
+	case float64(cpuRequest) > 2*cpu.p95 && float64(cpuRequest)-cpu.p95 > minCPUSlackMilli:
+		cpuOver = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("CPU request %s is more than twice the p95 usage %s", formatMilliCPU(cpuRequest), formatMilliCPU(int64(cpu.p95))))
+	}
+	if cpuLimit > 0 && cpu.max > 0.9*float64(cpuLimit) {
+		under = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("max CPU usage %s is close to the limit %s, the container is likely throttled", formatMilliCPU(int64(cpu.max)), formatMilliCPU(cpuLimit)))
+	}
+	switch {
+	case memoryRequest == 0:
+		under = true
+		entry.Findings = append(entry.Findings, "no memory request, the pod is among the first to be evicted under memory pressure")
+	case memory.max > float64(memoryRequest):
+		under = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("max memory usage %s is above the request %s", formatMemory(int64(memory.max)), formatMemory(memoryRequest)))
+	case float64(memoryRequest) > 2*memory.max && float64(memoryRequest)-memory.max > minMemorySlackByte:
+		memoryOver = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("memory request %s is more than twice the max usage %s", formatMemory(memoryRequest), formatMemory(int64(memory.max))))
+	}
+	if memoryLimit > 0 && memory.max > 0.9*float64(memoryLimit) {
+		under = true
+		entry.Findings = append(entry.Findings, fmt.Sprintf("max memory usage %s is close to the limit %s, the container risks being OOMKilled", formatMemory(int64(memory.max)), formatMemory(memoryLimit)))
+	}
+	switch {
+	case under:
+		entry.Status = RightsizingUnderProvisioned
+	case cpuOver || memoryOver:
+		entry.Status = RightsizingOverProvisioned
+	default:
+		entry.Status = RightsizingOK
+	}
+
+	// 只统计被标记为过度分配的requests降低的部分
+	var cpuSaved, memorySaved float64
+	if cpuOver {
+		cpuSaved = math.Max(0, float64(cpuRequest-suggestedCPURequest))
+	}
+	if memoryOver {
+		memorySaved = math.Max(0, float64(memoryRequest-suggestedMemoryRequest))
+	}
+	return entry, cpuSaved, memorySaved
+}
@@ -0,0 +1,398 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// Deployment的ReplicaSet上记录revision的annotation
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	// kubectl 记录变更原因的annotation
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// rolloutRevision 是一个历史版本，Deployment对应ReplicaSet，StatefulSet和DaemonSet对应ControllerRevision
+type rolloutRevision struct {
+	revision    int64
+	name        string
+	changeCause string
+	created     time.Time
+	// template 是这个版本的pod template
+	template map[string]interface{}
+	// patch 是ControllerRevision中保存的strategic merge patch，回滚时直接打到对象上
+	patch []byte
+}
+
+// RolloutStatus 获取Deployment、StatefulSet或DaemonSet的滚动更新状态
+// timeout大于0时会一直等待到滚动更新完成、失败或者超时
+// 返回更新、就绪、可用的副本数，以及不健康的conditions
+func (c *Client) RolloutStatus(ctx context.Context, kind, name, namespace string, timeout time.Duration) (map[string]interface{}, error) {
+	kind = c.canonicalKind(kind)
+	status, err := c.rolloutStatusOnce(ctx, kind, name, namespace)
+	if err != nil || timeout <= 0 {
+		return status, err
+	}
+	pollErr := wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		status, err = c.rolloutStatusOnce(ctx, kind, name, namespace)
+		if err != nil {
+			return false, err
+		}
+		return status["done"] == true || status["failed"] == true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pollErr != nil {
+		status["timedOut"] = true
+	}
+	return status, nil
+}
+
+func (c *Client) rolloutStatusOnce(ctx context.Context, kind, name, namespace string) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"kind":      kind,
+		"name":      name,
+		"namespace": namespace,
+		"done":      false,
+		"failed":    false,
+	}
+	switch kind {
+	case "Deployment":
+		deploy, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		desired := int32(1)
+		if deploy.Spec.Replicas != nil {
+			desired = *deploy.Spec.Replicas
+		}
+		st := deploy.Status
+		result["desired"] = desired
+		result["updated"] = st.UpdatedReplicas
+		result["ready"] = st.ReadyReplicas
+		result["available"] = st.AvailableReplicas
+		var failing []map[string]interface{}
+		for _, cond := range st.Conditions {
+			if (cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue) ||
+				(cond.Type != appsv1.DeploymentReplicaFailure && cond.Status != corev1.ConditionTrue) {
+				failing = append(failing, conditionInfo(string(cond.Type), string(cond.Status), cond.Reason, cond.Message, cond.LastUpdateTime.Time))
+			}
+			if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+				result["failed"] = true
+			}
+		}
+		result["failingConditions"] = failing
+		switch {
+		case deploy.Generation > st.ObservedGeneration:
+			result["message"] = "waiting for deployment spec update to be observed"
+		case result["failed"] == true:
+			result["message"] = fmt.Sprintf("deployment %q exceeded its progress deadline", name)
+		case st.UpdatedReplicas < desired:
+			result["message"] = fmt.Sprintf("%d out of %d new replicas have been updated", st.UpdatedReplicas, desired)
+		case st.Replicas > st.UpdatedReplicas:
+			result["message"] = fmt.Sprintf("%d old replicas are pending termination", st.Replicas-st.UpdatedReplicas)
+		case st.AvailableReplicas < st.UpdatedReplicas:
+			result["message"] = fmt.Sprintf("%d of %d updated replicas are available", st.AvailableReplicas, st.UpdatedReplicas)
+		default:
+			result["done"] = true
+			result["message"] = fmt.Sprintf("deployment %q successfully rolled out", name)
+		}
+	case "StatefulSet":
+		sts, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+		desired := int32(1)
+		if sts.Spec.Replicas != nil {
+			desired = *sts.Spec.Replicas
+		}
+		st := sts.Status
+		result["desired"] = desired
+		result["updated"] = st.UpdatedReplicas
+		result["ready"] = st.ReadyReplicas
+		result["available"] = st.AvailableReplicas
+		var failing []map[string]interface{}
+		for _, cond := range st.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				failing = append(failing, conditionInfo(string(cond.Type), string(cond.Status), cond.Reason, cond.Message, cond.LastTransitionTime.Time))
+			}
+		}
+		result["failingConditions"] = failing
+		partition := int32(0)
+		if sts.Spec.UpdateStrategy.RollingUpdate != nil && sts.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+			partition = *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+		}
+		switch {
+		case sts.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType:
+			result["message"] = "rollout status is only available for the RollingUpdate strategy"
+		case sts.Generation > st.ObservedGeneration:
+			result["message"] = "waiting for statefulset spec update to be observed"
+		case st.ReadyReplicas < desired:
+			result["message"] = fmt.Sprintf("%d of %d pods are ready", st.ReadyReplicas, desired)
+		case partition > 0 && st.UpdatedReplicas < desired-partition:
+			result["message"] = fmt.Sprintf("%d of %d pods above partition %d have been updated", st.UpdatedReplicas, desired-partition, partition)
+		case partition == 0 && st.UpdateRevision != st.CurrentRevision:
+			result["message"] = fmt.Sprintf("%d of %d pods have been updated to revision %s", st.UpdatedReplicas, desired, st.UpdateRevision)
+		default:
+			result["done"] = true
+			result["message"] = fmt.Sprintf("statefulset %q successfully rolled out", name)
+		}
+	case "DaemonSet":
+		ds, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s/%s: %w", namespace, name, err)
+		}
+		st := ds.Status
+		result["desired"] = st.DesiredNumberScheduled
+		result["updated"] = st.UpdatedNumberScheduled
+		result["ready"] = st.NumberReady
+		result["available"] = st.NumberAvailable
+		var failing []map[string]interface{}
+		for _, cond := range st.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				failing = append(failing, conditionInfo(string(cond.Type), string(cond.Status), cond.Reason, cond.Message, cond.LastTransitionTime.Time))
+			}
+		}
+		result["failingConditions"] = failing
+		switch {
+		case ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType:
+			result["message"] = "rollout status is only available for the RollingUpdate strategy"
+		case ds.Generation > st.ObservedGeneration:
+			result["message"] = "waiting for daemonset spec update to be observed"
+		case st.UpdatedNumberScheduled < st.DesiredNumberScheduled:
+			result["message"] = fmt.Sprintf("%d out of %d new pods have been updated", st.UpdatedNumberScheduled, st.DesiredNumberScheduled)
+		case st.NumberAvailable < st.DesiredNumberScheduled:
+			result["message"] = fmt.Sprintf("%d of %d updated pods are available", st.NumberAvailable, st.DesiredNumberScheduled)
+		default:
+			result["done"] = true
+			result["message"] = fmt.Sprintf("daemonset %q successfully rolled out", name)
+		}
+	default:
+		return nil, fmt.Errorf("rollout is not supported for kind %s, use Deployment, StatefulSet or DaemonSet", kind)
+	}
+	return result, nil
+}
+
+func conditionInfo(condType, status, reason, message string, lastTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     condType,
+		"status":   status,
+		"reason":   reason,
+		"message":  message,
+		"lastTime": lastTime,
+	}
+}
+
+// RolloutHistory 列出Deployment（ReplicaSet）或StatefulSet、DaemonSet（ControllerRevision）的历史版本
+// 每个版本包含change-cause，以及和上一个版本相比pod template的差异
+// revision大于0时额外返回该版本完整的pod template
+func (c *Client) RolloutHistory(ctx context.Context, kind, name, namespace string, revision int64) (map[string]interface{}, error) {
+	kind = c.canonicalKind(kind)
+	revisions, err := c.rolloutRevisions(ctx, kind, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]map[string]interface{}, 0, len(revisions))
+	var found *rolloutRevision
+	for i, rev := range revisions {
+		item := map[string]interface{}{
+			"revision":    rev.revision,
+			"name":        rev.name,
+			"changeCause": nilIfEmpty(rev.changeCause),
+			"created":     rev.created,
+		}
+		if i > 0 {
+			item["templateDiff"] = DiffObjects(revisions[i-1].template, rev.template)
+		}
+		history = append(history, item)
+		if rev.revision == revision {
+			found = &revisions[i]
+		}
+	}
+	result := map[string]interface{}{
+		"kind":      kind,
+		"name":      name,
+		"namespace": namespace,
+		"history":   history,
+	}
+	if revision > 0 {
+		if found == nil {
+			return nil, fmt.Errorf("revision %d not found for %s %s/%s", revision, kind, namespace, name)
+		}
+		result["template"] = found.template
+	}
+	return result, nil
+}
+
+// RolloutUndo 把Deployment、StatefulSet或DaemonSet回滚到指定的版本，toRevision为0时回滚到上一个版本
+func (c *Client) RolloutUndo(ctx context.Context, kind, name, namespace string, toRevision int64) (map[string]interface{}, error) {
+	kind = c.canonicalKind(kind)
+	revisions, err := c.rolloutRevisions(ctx, kind, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("no rollout history found for %s %s/%s", kind, namespace, name)
+	}
+	current := revisions[len(revisions)-1]
+	var target *rolloutRevision
+	if toRevision == 0 {
+		if len(revisions) < 2 {
+			return nil, fmt.Errorf("no previous revision to roll back to for %s %s/%s", kind, namespace, name)
+		}
+		target = &revisions[len(revisions)-2]
+	} else {
+		for i := range revisions {
+			if revisions[i].revision == toRevision {
+				target = &revisions[i]
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("revision %d not found for %s %s/%s", toRevision, kind, namespace, name)
+		}
+		if target.revision == current.revision {
+			return nil, fmt.Errorf("%s %s/%s is already at revision %d", kind, namespace, name, toRevision)
+		}
+	}
+
+	var patchType types.PatchType
+	var patch []byte
+	switch kind {
+	case "Deployment":
+		// 用JSON patch整体替换template，避免strategic merge把容器列表合并
+		patchType = types.JSONPatchType
+		patch, err = json.Marshal([]map[string]interface{}{
+			{"op": "replace", "path": "/spec/template", "value": target.template},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build rollback patch: %w", err)
+		}
+	default:
+		patchType = types.StrategicMergePatchType
+		patch = target.patch
+	}
+
+	gvr, err := c.getCachedGVR(kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gvr for kind %s :%w", kind, err)
+	}
+	patched, err := c.dynamicClient.Resource(*gvr).Namespace(namespace).Patch(ctx, name, patchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back %s %s/%s :%w", kind, namespace, name, err)
+	}
+	return map[string]interface{}{
+		"kind":            kind,
+		"name":            name,
+		"namespace":       namespace,
+		"fromRevision":    current.revision,
+		"toRevision":      target.revision,
+		"templateDiff":    DiffObjects(current.template, target.template),
+		"resourceVersion": patched.GetResourceVersion(),
+	}, nil
+}
+
+// rolloutRevisions 返回对象的所有历史版本，按revision升序排列
+func (c *Client) rolloutRevisions(ctx context.Context, kind, name, namespace string) ([]rolloutRevision, error) {
+	var revisions []rolloutRevision
+	switch kind {
+	case "Deployment":
+		deploy, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of deployment %s/%s: %w", namespace, name, err)
+		}
+		rsList, err := c.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replicasets: %w", err)
+		}
+		for _, rs := range rsList.Items {
+			if !metav1.IsControlledBy(&rs, deploy) {
+				continue
+			}
+			revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+			if err != nil {
+				continue
+			}
+			template := rs.Spec.Template.DeepCopy()
+			delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert template of replicaset %s: %w", rs.Name, err)
+			}
+			revisions = append(revisions, rolloutRevision{
+				revision:    revision,
+				name:        rs.Name,
+				changeCause: rs.Annotations[changeCauseAnnotation],
+				created:     rs.CreationTimestamp.Time,
+				template:    content,
+			})
+		}
+	case "StatefulSet", "DaemonSet":
+		var owner metav1.Object
+		var labelSelector *metav1.LabelSelector
+		if kind == "StatefulSet" {
+			sts, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+			}
+			owner, labelSelector = sts, sts.Spec.Selector
+		} else {
+			ds, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get daemonset %s/%s: %w", namespace, name, err)
+			}
+			owner, labelSelector = ds, ds.Spec.Selector
+		}
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of %s %s/%s: %w", kind, namespace, name, err)
+		}
+		crList, err := c.Clientset.AppsV1().ControllerRevisions(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list controllerrevisions: %w", err)
+		}
+		for _, cr := range crList.Items {
+			if !metav1.IsControlledBy(&cr, owner) {
+				continue
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(cr.Data.Raw, &data); err != nil {
+				return nil, fmt.Errorf("failed to parse controllerrevision %s: %w", cr.Name, err)
+			}
+			template, _, _ := unstructured.NestedMap(data, "spec", "template")
+			if template != nil {
+				delete(template, "$patch")
+			}
+			revisions = append(revisions, rolloutRevision{
+				revision:    cr.Revision,
+				name:        cr.Name,
+				changeCause: cr.Annotations[changeCauseAnnotation],
+				created:     cr.CreationTimestamp.Time,
+				template:    template,
+				patch:       cr.Data.Raw,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("rollout is not supported for kind %s, use Deployment, StatefulSet or DaemonSet", kind)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].revision < revisions[j].revision
+	})
+	return revisions, nil
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// 这些kind的status中带有readyReplicas，等待扩缩容完成时以它为准
+// 其他带scale子资源的kind只能以scale子资源的status.replicas为准
+var readyReplicasKinds = map[string]struct{}{
+	"Deployment":            {},
+	"StatefulSet":           {},
+	"ReplicaSet":            {},
+	"ReplicationController": {},
+	"Rollout":               {},
+}
+
+// parseReplicas 解析副本数，"3" 表示绝对值，"+2"、"-1" 表示在current基础上增减
+func parseReplicas(spec string, current int64) (int64, error) {
+	spec = strings.TrimSpace(spec)
+	value, err := strconv.ParseInt(spec, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid replicas %q, use an absolute count like 3 or a relative change like +2 or -1", spec)
+	}
+	desired := value
+	if strings.HasPrefix(spec, "+") || strings.HasPrefix(spec, "-") {
+		desired = current + value
+	}
+	if desired < 0 {
+		return 0, fmt.Errorf("replicas %q results in a negative replica count %d", spec, desired)
+	}
+	return desired, nil
+}
+
+// ScaleResource 通过scale子资源修改副本数，适用于所有暴露了/scale的kind（包括Argo Rollouts等CRD）
+// replicas 可以是绝对值也可以是相对值；waitReady为true时会等待就绪副本数收敛到期望值，最多等待timeout
+// 返回扩缩容前后的副本数，以及等待的结果
+func (c *Client) ScaleResource(ctx context.Context, kind, name, namespace, replicas string, waitReady bool, timeout time.Duration) (map[string]interface{}, error) {
+	gvr, err := c.getCachedGVR(kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gvr for kind %s :%w", kind, err)
+	}
+	resource := c.dynamicClient.Resource(*gvr).Namespace(namespace)
+
+	scale, err := resource.Get(ctx, name, metav1.GetOptions{}, "scale")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scale of %s %s/%s, the kind may not support the scale subresource: %w", kind, namespace, name, err)
+	}
+	before, _, _ := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	desired, err := parseReplicas(replicas, before)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"kind":      kind,
+		"name":      name,
+		"namespace": namespace,
+		"before":    before,
+		"after":     desired,
+	}
+	if desired != before {
+		patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, desired))
+		scaled, err := resource.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+		if err != nil {
+			return nil, fmt.Errorf("failed to scale %s %s/%s :%w", kind, namespace, name, err)
+		}
+		result["resourceVersion"] = scaled.GetResourceVersion()
+	}
+	if !waitReady {
+		return result, nil
+	}
+
+	var ready int64
+	_, useReadyReplicas := readyReplicasKinds[c.canonicalKind(kind)]
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		if useReadyReplicas {
+			obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, nil
+			}
+			// 缩容到0时readyReplicas字段会被省略
+			ready, _, _ = unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		} else {
+			scale, err := resource.Get(ctx, name, metav1.GetOptions{}, "scale")
+			if err != nil {
+				return false, nil
+			}
+			ready, _, _ = unstructured.NestedInt64(scale.Object, "status", "replicas")
+		}
+		return ready == desired, nil
+	})
+	result["readyReplicas"] = ready
+	result["converged"] = err == nil
+	if err != nil {
+		result["message"] = fmt.Sprintf("ready replicas did not converge to %d within %s", desired, timeout)
+	}
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// 除了metadata.name和metadata.namespace之外，可以在缓存中本地计算的字段选择器
+// 只包含API Server对这些kind同样支持的字段，其他字段选择器仍然交给API Server
+var cachedFieldSelectors = map[string]map[string][]string{
+	"Pod": {
+		"status.phase":  {"status", "phase"},
+		"spec.nodeName": {"spec", "nodeName"},
+	},
+	"Namespace": {
+		"status.phase": {"status", "phase"},
+	},
+	"Event": {
+		"involvedObject.kind":      {"involvedObject", "kind"},
+		"involvedObject.name":      {"involvedObject", "name"},
+		"involvedObject.namespace": {"involvedObject", "namespace"},
+		"reason":                   {"reason"},
+		"type":                     {"type"},
+	},
+}
+
+// parseCachedFieldSelector 解析字段选择器，第二个返回值表示它是否可以在缓存中计算
+func parseCachedFieldSelector(kind, fieldSelector string) (fields.Selector, bool) {
+	if fieldSelector == "" {
+		return fields.Everything(), true
+	}
+	selector, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, false
+	}
+	for _, requirement := range selector.Requirements() {
+		if requirement.Field == "metadata.name" || requirement.Field == "metadata.namespace" {
+			continue
+		}
+		if _, ok := cachedFieldSelectors[kind][requirement.Field]; !ok {
+			return nil, false
+		}
+	}
+	return selector, true
+}
+
+// cachedFieldSet 返回对象上可以被字段选择器匹配的字段
+func cachedFieldSet(kind string, obj *unstructured.Unstructured) fields.Set {
+	set := fields.Set{
+		"metadata.name":      obj.GetName(),
+		"metadata.namespace": obj.GetNamespace(),
+	}
+	for field, path := range cachedFieldSelectors[kind] {
+		value, _, _ := unstructured.NestedString(obj.Object, path...)
+		set[field] = value
+	}
+	return set
+}
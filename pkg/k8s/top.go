@@ -0,0 +1,296 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// top 默认返回的数量
+const DefaultTopLimit = 20
+
+// top 支持的排序方式，百分比对Pod是占limit的比例，对节点是占allocatable的比例
+const (
+	TopSortCPU           = "cpu"
+	TopSortMemory        = "memory"
+	TopSortCPUPercent    = "cpuPercent"
+	TopSortMemoryPercent = "memoryPercent"
+)
+
+// TopOptions 是topPods和topNodes的选项，Limit为0时返回全部
+type TopOptions struct {
+	// Namespace 为空表示所有命名空间，只对Pod有效
+	Namespace     string
+	LabelSelector string
+	SortBy        string
+	Limit         int
+}
+
+// TopResult 是排序并截断后的结果，Total是截断前的数量
+type TopResult struct {
+	SortBy string                   `json:"sortBy"`
+	Total  int                      `json:"total"`
+	Items  []map[string]interface{} `json:"items"`
+}
+
+// topRow 是排序使用的数值，percent为负数表示没有limit或allocatable
+type topRow struct {
+	cpu, memory               int64
+	cpuPercent, memoryPercent float64
+	item                      map[string]interface{}
+}
+
+func formatMilliCPU(milli int64) string {
+	return fmt.Sprintf("%dm", milli)
+}
+
+func formatMemory(bytes int64) string {
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
+
+// percent 返回usage占total的百分比（保留一位小数），total为0时返回-1
+func percent(usage, total int64) float64 {
+	if total <= 0 {
+		return -1
+	}
+	return math.Round(float64(usage)*1000/float64(total)) / 10
+}
+
+func setPercent(item map[string]interface{}, key string, value float64) {
+	if value >= 0 {
+		item[key] = value
+	}
+}
+
+// sortTopRows 按sortBy从大到小排序，没有百分比的行排在最后，然后取前limit个
+func sortTopRows(rows []topRow, opts TopOptions) (*TopResult, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = TopSortCPU
+	}
+	var value func(row topRow) float64
+	switch sortBy {
+	case TopSortCPU:
+		value = func(row topRow) float64 { return float64(row.cpu) }
+	case TopSortMemory:
+		value = func(row topRow) float64 { return float64(row.memory) }
+	case TopSortCPUPercent:
+		value = func(row topRow) float64 { return row.cpuPercent }
+	case TopSortMemoryPercent:
+		value = func(row topRow) float64 { return row.memoryPercent }
+	default:
+		return nil, fmt.Errorf("invalid sortBy %q, use one of %s, %s, %s, %s", sortBy, TopSortCPU, TopSortMemory, TopSortCPUPercent, TopSortMemoryPercent)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return value(rows[i]) > value(rows[j])
+	})
+	result := &TopResult{SortBy: sortBy, Total: len(rows), Items: []map[string]interface{}{}}
+	for i, row := range rows {
+		if opts.Limit > 0 && i >= opts.Limit {
+			break
+		}
+		result.Items = append(result.Items, row.item)
+	}
+	return result, nil
+}
+
+// podResources 汇总Pod中容器的requests和limits，有容器没有设置limit时对应的limit为0（不限制）
+func podResources(pod *corev1.Pod) (cpuRequest, cpuLimit, memoryRequest, memoryLimit int64) {
+	cpuLimited, memoryLimited := true, true
+	for _, container := range pod.Spec.Containers {
+		cpuRequest += container.Resources.Requests.Cpu().MilliValue()
+		memoryRequest += container.Resources.Requests.Memory().Value()
+		if limit, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+			cpuLimit += limit.MilliValue()
+		} else {
+			cpuLimited = false
+		}
+		if limit, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+			memoryLimit += limit.Value()
+		} else {
+			memoryLimited = false
+		}
+	}
+	if !cpuLimited {
+		cpuLimit = 0
+	}
+	if !memoryLimited {
+		memoryLimit = 0
+	}
+	return
+}
+
+// listPods 从缓存或API Server列出Pod
+func (c *Client) listPods(ctx context.Context, namespace, labelSelector string) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	if cached, found := c.listResourcesFromCache("Pod", namespace, labelSelector, ""); found {
+		for _, item := range cached {
+			pod := corev1.Pod{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &pod); err == nil {
+				pods = append(pods, pod)
+			}
+		}
+		return pods, nil
+	}
+	list, err := c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return list.Items, nil
+}
+
+// TopPods 和kubectl top pods类似，列出metrics.k8s.io中的Pod用量，并加上requests、limits和用量占它们的百分比
+func (c *Client) TopPods(ctx context.Context, opts TopOptions) (*TopResult, error) {
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", opts.LabelSelector, err)
+	}
+	metricsList, err := c.metricsClient.MetricsV1beta1().PodMetricses(opts.Namespace).List(ctx, metav1.ListOptions{LabelSelector: opts.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics, is metrics-server installed?: %w", err)
+	}
+	pods, err := c.listPods(ctx, opts.Namespace, opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	podsByKey := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podsByKey[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+
+	rows := make([]topRow, 0, len(metricsList.Items))
+	for _, metrics := range metricsList.Items {
+		var cpu, memory int64
+		for _, container := range metrics.Containers {
+			cpu += container.Usage.Cpu().MilliValue()
+			memory += container.Usage.Memory().Value()
+		}
+		item := map[string]interface{}{
+			"namespace": metrics.Namespace,
+			"name":      metrics.Name,
+			"cpu":       formatMilliCPU(cpu),
+			"memory":    formatMemory(memory),
+		}
+		row := topRow{cpu: cpu, memory: memory, cpuPercent: -1, memoryPercent: -1, item: item}
+		if pod, ok := podsByKey[metrics.Namespace+"/"+metrics.Name]; ok {
+			cpuRequest, cpuLimit, memoryRequest, memoryLimit := podResources(pod)
+			item["node"] = pod.Spec.NodeName
+			if cpuRequest > 0 {
+				item["cpuRequest"] = formatMilliCPU(cpuRequest)
+				setPercent(item, "cpuPercentOfRequest", percent(cpu, cpuRequest))
+			}
+			if cpuLimit > 0 {
+				item["cpuLimit"] = formatMilliCPU(cpuLimit)
+				row.cpuPercent = percent(cpu, cpuLimit)
+				setPercent(item, "cpuPercentOfLimit", row.cpuPercent)
+			}
+			if memoryRequest > 0 {
+				item["memoryRequest"] = formatMemory(memoryRequest)
+				setPercent(item, "memoryPercentOfRequest", percent(memory, memoryRequest))
+			}
+			if memoryLimit > 0 {
+				item["memoryLimit"] = formatMemory(memoryLimit)
+				row.memoryPercent = percent(memory, memoryLimit)
+				setPercent(item, "memoryPercentOfLimit", row.memoryPercent)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return sortTopRows(rows, opts)
+}
+
+// TopNodes 和kubectl top nodes类似，列出节点用量占allocatable的比例，以及节点上Pod的requests总和
+func (c *Client) TopNodes(ctx context.Context, opts TopOptions) (*TopResult, error) {
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", opts.LabelSelector, err)
+	}
+	metricsList, err := c.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{LabelSelector: opts.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node metrics, is metrics-server installed?: %w", err)
+	}
+	var nodes []corev1.Node
+	if cached, found := c.listResourcesFromCache("Node", "", opts.LabelSelector, ""); found {
+		for _, item := range cached {
+			node := corev1.Node{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &node); err == nil {
+				nodes = append(nodes, node)
+			}
+		}
+	} else {
+		list, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: opts.LabelSelector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %w", err)
+		}
+		nodes = list.Items
+	}
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].Name] = &nodes[i]
+	}
+
+	// 和kubectl describe node一样，只统计没有结束的Pod的requests
+	type nodeRequests struct {
+		cpu, memory int64
+		pods        int
+	}
+	requests := map[string]*nodeRequests{}
+	pods, err := c.listPods(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		r := requests[pod.Spec.NodeName]
+		if r == nil {
+			r = &nodeRequests{}
+			requests[pod.Spec.NodeName] = r
+		}
+		cpuRequest, _, memoryRequest, _ := podResources(pod)
+		r.cpu += cpuRequest
+		r.memory += memoryRequest
+		r.pods++
+	}
+
+	rows := make([]topRow, 0, len(metricsList.Items))
+	for _, metrics := range metricsList.Items {
+		cpu := metrics.Usage.Cpu().MilliValue()
+		memory := metrics.Usage.Memory().Value()
+		item := map[string]interface{}{
+			"name":   metrics.Name,
+			"cpu":    formatMilliCPU(cpu),
+			"memory": formatMemory(memory),
+		}
+		row := topRow{cpu: cpu, memory: memory, cpuPercent: -1, memoryPercent: -1, item: item}
+		if node, ok := nodesByName[metrics.Name]; ok {
+			allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+			allocatableMemory := node.Status.Allocatable.Memory().Value()
+			item["cpuAllocatable"] = formatMilliCPU(allocatableCPU)
+			item["memoryAllocatable"] = formatMemory(allocatableMemory)
+			row.cpuPercent = percent(cpu, allocatableCPU)
+			row.memoryPercent = percent(memory, allocatableMemory)
+			setPercent(item, "cpuPercent", row.cpuPercent)
+			setPercent(item, "memoryPercent", row.memoryPercent)
+			item["ready"] = nodeReady(node)
+			if node.Spec.Unschedulable {
+				item["unschedulable"] = true
+			}
+			if r, ok := requests[node.Name]; ok {
+				item["pods"] = r.pods
+				item["cpuRequests"] = formatMilliCPU(r.cpu)
+				item["memoryRequests"] = formatMemory(r.memory)
+				setPercent(item, "cpuRequestsPercent", percent(r.cpu, allocatableCPU))
+				setPercent(item, "memoryRequestsPercent", percent(r.memory, allocatableMemory))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return sortTopRows(rows, opts)
+}
@@ -0,0 +1,227 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// 订阅中推送的变化类型
+const (
+	ChangeAdded    = "ADDED"
+	ChangeModified = "MODIFIED"
+	ChangeDeleted  = "DELETED"
+	ChangeWarning  = "WARNING"
+)
+
+// WatchOptions 描述要订阅的对象
+type WatchOptions struct {
+	Kind string `json:"kind"`
+	// Namespace 为空时订阅所有命名空间
+	Namespace     string `json:"namespace,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// WarningEvents 为true时同时推送与这些对象相关的Warning事件
+	WarningEvents bool `json:"warningEvents"`
+}
+
+// ResourceChange 是informer观察到的一次对象变化
+// MODIFIED 只带有变化的字段，WARNING 带有事件的reason和message
+type ResourceChange struct {
+	Type            string        `json:"type"`
+	Kind            string        `json:"kind"`
+	Namespace       string        `json:"namespace,omitempty"`
+	Name            string        `json:"name"`
+	ResourceVersion string        `json:"resourceVersion,omitempty"`
+	Diff            []FieldChange `json:"diff,omitempty"`
+	Reason          string        `json:"reason,omitempty"`
+	Message         string        `json:"message,omitempty"`
+	Count           int64         `json:"count,omitempty"`
+}
+
+// WatchResources 在kind对应的informer上注册事件处理函数，对象每次新增、修改、删除都会调用onChange
+// 注册时已经存在的对象不会触发回调；informer定期resync产生的没有实际变化的更新也会被忽略
+// 返回的函数用于取消订阅；模拟其他身份的客户端先检查list/watch权限，再使用base的informer
+func (c *Client) WatchResources(ctx context.Context, opts WatchOptions, onChange func(ResourceChange)) (func(), error) {
+	if c.base != nil {
+		if err := c.checkWatchAccess(ctx, opts); err != nil {
+			return nil, err
+		}
+		return c.base.WatchResources(ctx, opts, onChange)
+	}
+	selector := labels.Everything()
+	if opts.LabelSelector != "" {
+		parsed, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labelSelector %q: %w", opts.LabelSelector, err)
+		}
+		selector = parsed
+	}
+
+	resource, err := c.resolveAPIResource(opts.Kind)
+	if err != nil {
+		return nil, err
+	}
+	// 推送和事件匹配都使用Kind名称
+	opts.Kind = resource.kind
+
+	c.informerLock.RLock()
+	informer, ok := c.resourceInformers[resource.key]
+	eventInformer, hasEvents := c.resourceInformers["Event"]
+	c.informerLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("kind %s is not watchable, it does not exist or does not support list/watch", opts.Kind)
+	}
+	if opts.WarningEvents && !hasEvents {
+		return nil, fmt.Errorf("events are not watchable in this cluster")
+	}
+
+	matches := func(obj *unstructured.Unstructured) bool {
+		if opts.Namespace != "" && obj.GetNamespace() != opts.Namespace {
+			return false
+		}
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	}
+	notify := func(changeType string, obj *unstructured.Unstructured, diff []FieldChange) {
+		onChange(ResourceChange{
+			Type:            changeType,
+			Kind:            opts.Kind,
+			Namespace:       obj.GetNamespace(),
+			Name:            obj.GetName(),
+			ResourceVersion: obj.GetResourceVersion(),
+			Diff:            diff,
+		})
+	}
+
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok || isInInitialList || !matches(u) {
+				return
+			}
+			notify(ChangeAdded, u, nil)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU, ok1 := oldObj.(*unstructured.Unstructured)
+			newU, ok2 := newObj.(*unstructured.Unstructured)
+			if !ok1 || !ok2 {
+				return
+			}
+			// 标签变化导致对象移出或移入selector时也要推送
+			oldMatched, newMatched := matches(oldU), matches(newU)
+			switch {
+			case !oldMatched && !newMatched:
+				return
+			case oldMatched && !newMatched:
+				notify(ChangeDeleted, newU, nil)
+				return
+			case !oldMatched && newMatched:
+				notify(ChangeAdded, newU, nil)
+				return
+			}
+			diff := diffObjects(oldU.Object, newU.Object, watchIgnoredPaths)
+			if len(diff) == 0 {
+				return
+			}
+			notify(ChangeModified, newU, diff)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok || !matches(u) {
+				return
+			}
+			notify(ChangeDeleted, u, nil)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", opts.Kind, err)
+	}
+	if !opts.WarningEvents {
+		return func() { _ = informer.RemoveEventHandler(registration) }, nil
+	}
+
+	eventRegistration, err := eventInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				c.handleWarningEvent(opts, selector, obj, onChange)
+			}
+		},
+		// 重复出现的事件只会更新count和时间
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU, ok := oldObj.(*unstructured.Unstructured)
+			newU, ok2 := newObj.(*unstructured.Unstructured)
+			if ok && ok2 && oldU.GetResourceVersion() == newU.GetResourceVersion() {
+				return
+			}
+			c.handleWarningEvent(opts, selector, newObj, onChange)
+		},
+	})
+	if err != nil {
+		_ = informer.RemoveEventHandler(registration)
+		return nil, fmt.Errorf("failed to watch events: %w", err)
+	}
+	return func() {
+		_ = informer.RemoveEventHandler(registration)
+		_ = eventInformer.RemoveEventHandler(eventRegistration)
+	}, nil
+}
+
+// handleWarningEvent 判断事件是否是订阅对象的Warning事件，是的话推送
+// core/v1 和 events.k8s.io/v1 的Event字段名不同，两种都需要支持
+func (c *Client) handleWarningEvent(opts WatchOptions, selector labels.Selector, obj interface{}, onChange func(ResourceChange)) {
+	event, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if eventType, _, _ := unstructured.NestedString(event.Object, "type"); eventType != "Warning" {
+		return
+	}
+	involved, found, _ := unstructured.NestedMap(event.Object, "involvedObject")
+	if !found {
+		involved, _, _ = unstructured.NestedMap(event.Object, "regarding")
+	}
+	kind, _, _ := unstructured.NestedString(involved, "kind")
+	name, _, _ := unstructured.NestedString(involved, "name")
+	namespace, _, _ := unstructured.NestedString(involved, "namespace")
+	if kind != opts.Kind {
+		return
+	}
+	if opts.Namespace != "" && namespace != opts.Namespace {
+		return
+	}
+	// 事件本身不带对象的标签，需要从缓存中取出对象再匹配
+	if !selector.Empty() {
+		obj, found := c.getResourceFromCache(kind, namespace, name)
+		if !found {
+			return
+		}
+		if !selector.Matches(labels.Set((&unstructured.Unstructured{Object: obj}).GetLabels())) {
+			return
+		}
+	}
+
+	message, found, _ := unstructured.NestedString(event.Object, "message")
+	if !found {
+		message, _, _ = unstructured.NestedString(event.Object, "note")
+	}
+	reason, _, _ := unstructured.NestedString(event.Object, "reason")
+	count, found, _ := unstructured.NestedInt64(event.Object, "count")
+	if !found {
+		count, _, _ = unstructured.NestedInt64(event.Object, "series", "count")
+	}
+	onChange(ResourceChange{
+		Type:            ChangeWarning,
+		Kind:            kind,
+		Namespace:       namespace,
+		Name:            name,
+		ResourceVersion: event.GetResourceVersion(),
+		Reason:          reason,
+		Message:         message,
+		Count:           count,
+	})
+}
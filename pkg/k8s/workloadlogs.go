@@ -0,0 +1,241 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// 一次最多读取日志的Pod数量
+const maxWorkloadLogPods = 50
+
+// 同时读取日志的容器数量
+const workloadLogConcurrency = 8
+
+// WorkloadLogTarget 指定要读取日志的Pod：Kind和Name指向一个带有spec.selector的工作负载
+// （Deployment、StatefulSet、DaemonSet、ReplicaSet、Job等），也可以只给LabelSelector，两者都给时取交集
+type WorkloadLogTarget struct {
+	Kind          string
+	Name          string
+	LabelSelector string
+}
+
+// logStream 是一个容器的日志
+type logStream struct {
+	prefix string
+	logs   string
+	err    error
+}
+
+// logLine 是合并时使用的一行日志
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// GetWorkloadLogs 和stern类似，并发读取所有匹配的Pod和容器的日志，按时间合并为一个带 [pod/container] 前缀的流
+// 为了合并，总是向服务器请求带时间戳的日志，options.Timestamps为false时输出中去掉时间戳
+// 字节预算作用于合并后的结果，超出时丢弃最早的行
+func (c *Client) GetWorkloadLogs(ctx context.Context, namespace string, target WorkloadLogTarget, options PodLogOptions) (string, error) {
+	selector, err := c.workloadSelector(ctx, namespace, target)
+	if err != nil {
+		return "", err
+	}
+	pods, err := c.selectorPods(ctx, namespace, selector)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pods match selector %q in namespace %s", selector.String(), namespace)
+	}
+	skippedPods := 0
+	if len(pods) > maxWorkloadLogPods {
+		skippedPods = len(pods) - maxWorkloadLogPods
+		pods = pods[:maxWorkloadLogPods]
+	}
+
+	podLogOptions, err := options.toPodLogOptions()
+	if err != nil {
+		return "", err
+	}
+	podLogOptions.Timestamps = true
+	filter, err := newLogFilter(options.Grep, options.ContextLines, true)
+	if err != nil {
+		return "", err
+	}
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultLogBytes
+	}
+
+	type streamTarget struct {
+		pod       string
+		container string
+	}
+	var targets []streamTarget
+	var streams []logStream
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if options.Container != "" && container.Name != options.Container {
+				continue
+			}
+			prefix := pod.Name
+			if len(pod.Spec.Containers) > 1 {
+				prefix += "/" + container.Name
+			}
+			targets = append(targets, streamTarget{pod: pod.Name, container: container.Name})
+			streams = append(streams, logStream{prefix: prefix})
+		}
+	}
+	if len(targets) == 0 {
+		return "", fmt.Errorf("no container named %s in the matching pods", options.Container)
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workloadLogConcurrency)
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, pod, container string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			containerOptions := podLogOptions.DeepCopy()
+			containerOptions.Container = container
+			// 每个容器各自保留最新的maxBytes，合并后再整体截断
+			streams[i].logs, streams[i].err = c.readPodLogs(ctx, namespace, pod, containerOptions, filter, maxBytes)
+		}(i, t.pod, t.container)
+	}
+	wg.Wait()
+
+	merged, notes := mergeLogStreams(streams, options.Timestamps)
+	buffer := &logBuffer{maxBytes: maxBytes}
+	for _, line := range merged {
+		buffer.add(line)
+	}
+	var out strings.Builder
+	if skippedPods > 0 {
+		fmt.Fprintf(&out, "--- %d more pods match, only the first %d are included, narrow the selector ---\n", skippedPods, maxWorkloadLogPods)
+	}
+	for _, note := range notes {
+		out.WriteString(note)
+	}
+	out.WriteString(buffer.String())
+	for _, stream := range streams {
+		if stream.err != nil {
+			fmt.Fprintf(&out, "--- Error getting logs for %s: %v ---\n", stream.prefix, stream.err)
+		}
+	}
+	return out.String(), nil
+}
+
+// mergeLogStreams 按时间戳合并多个容器的日志，同一时间的行保持各自的顺序
+// grep上下文的分隔行合并后没有意义，直接丢弃；每个容器的截断提示带上前缀单独返回，放在合并结果的前面
+func mergeLogStreams(streams []logStream, keepTimestamps bool) ([]string, []string) {
+	var lines []logLine
+	var notes []string
+	for _, stream := range streams {
+		if stream.err != nil {
+			continue
+		}
+		var last time.Time
+		for _, text := range strings.Split(stream.logs, "\n") {
+			if text == "" || text == "--" {
+				continue
+			}
+			// 日志行都以时间戳开头，只有logBuffer的截断提示以 ... 开头
+			if strings.HasPrefix(text, "... ") {
+				notes = append(notes, fmt.Sprintf("[%s] %s\n", stream.prefix, text))
+				continue
+			}
+			ts, message, found := strings.Cut(text, " ")
+			parsed, err := time.Parse(time.RFC3339Nano, ts)
+			if !found || err != nil {
+				// 没有时间戳的行跟在上一行后面
+				parsed, message = last, text
+			} else {
+				last = parsed
+			}
+			if keepTimestamps {
+				message = parsed.UTC().Format(time.RFC3339Nano) + " " + message
+			}
+			lines = append(lines, logLine{time: parsed, text: fmt.Sprintf("[%s] %s\n", stream.prefix, message)})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].time.Before(lines[j].time)
+	})
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, line.text)
+	}
+	return result, notes
+}
+
+// workloadSelector 返回工作负载spec.selector和labelSelector的交集
+func (c *Client) workloadSelector(ctx context.Context, namespace string, target WorkloadLogTarget) (labels.Selector, error) {
+	selector := labels.Everything()
+	if target.Kind != "" || target.Name != "" {
+		if target.Kind == "" || target.Name == "" {
+			return nil, fmt.Errorf("kind and name must be given together")
+		}
+		obj, err := c.GetResource(ctx, target.Kind, target.Name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		raw, found, _ := unstructured.NestedMap(obj, "spec", "selector")
+		if !found {
+			return nil, fmt.Errorf("%s %s has no spec.selector, use labelSelector instead", target.Kind, target.Name)
+		}
+		labelSelector := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, labelSelector); err != nil || (len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0) {
+			// Service和ReplicationController的spec.selector是普通的map
+			matchLabels := map[string]string{}
+			for key, value := range raw {
+				if s, ok := value.(string); ok {
+					matchLabels[key] = s
+				}
+			}
+			labelSelector = &metav1.LabelSelector{MatchLabels: matchLabels}
+		}
+		selector, err = metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of %s %s: %w", target.Kind, target.Name, err)
+		}
+		if selector.Empty() {
+			return nil, fmt.Errorf("%s %s has an empty selector", target.Kind, target.Name)
+		}
+	}
+	if target.LabelSelector != "" {
+		extra, err := labels.Parse(target.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", target.LabelSelector, err)
+		}
+		requirements, _ := extra.Requirements()
+		selector = selector.Add(requirements...)
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("either kind and name or labelSelector is required")
+	}
+	return selector, nil
+}
+
+// selectorPods 返回匹配selector的Pod，按名称排序
+func (c *Client) selectorPods(ctx context.Context, namespace string, selector labels.Selector) ([]corev1.Pod, error) {
+	pods, err := c.listPods(ctx, namespace, selector.String())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
@@ -0,0 +1,218 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// 规则的效果
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Rule 是策略中的一条规则，所有非空的字段都匹配时规则才生效，空字段匹配任何值
+// 每个字段都是glob列表（* 匹配任意字符串，? 匹配单个字符），列表中任意一个匹配即可
+// Kinds使用规范的Kind名称（例如 Deployment），大小写不敏感；Groups匹配调用方的任意一个组
+type Rule struct {
+	Name        string   `json:"name"`
+	Effect      string   `json:"effect"`
+	Description string   `json:"description,omitempty"`
+	Tools       []string `json:"tools,omitempty"`
+	Verbs       []string `json:"verbs,omitempty"`
+	Kinds       []string `json:"kinds,omitempty"`
+	Namespaces  []string `json:"namespaces,omitempty"`
+	Clusters    []string `json:"clusters,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+}
+
+// Policy 按顺序匹配规则，第一条匹配的规则决定结果，没有规则匹配时使用DefaultEffect
+type Policy struct {
+	DefaultEffect string `json:"defaultEffect"`
+	Rules         []Rule `json:"rules"`
+}
+
+// Request 是一次工具调用对一个对象的操作，Kind为空表示工具不针对具体的资源
+type Request struct {
+	Tool      string
+	Verb      string
+	Kind      string
+	Namespace string
+	// AllNamespaces 表示Kind是命名空间级别的资源但没有指定命名空间，操作涉及所有命名空间
+	AllNamespaces bool
+	Cluster       string
+	User          string
+	Groups        []string
+}
+
+// Decision 是策略的判断结果，Rule为空表示使用了默认效果
+type Decision struct {
+	Allowed     bool
+	Rule        string
+	Description string
+}
+
+// Load 读取YAML或JSON格式的策略文件
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+func (p *Policy) validate() error {
+	if p.DefaultEffect != Allow && p.DefaultEffect != Deny {
+		return fmt.Errorf("defaultEffect must be %q or %q", Allow, Deny)
+	}
+	names := map[string]bool{}
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return fmt.Errorf("rule %q: effect must be %q or %q", rule.Name, Allow, Deny)
+		}
+	}
+	return nil
+}
+
+// Evaluate 返回第一条匹配的规则的效果
+func (p *Policy) Evaluate(request Request) Decision {
+	for _, rule := range p.Rules {
+		if rule.matches(request) {
+			return Decision{Allowed: rule.Effect == Allow, Rule: rule.Name, Description: rule.Description}
+		}
+	}
+	return Decision{Allowed: p.DefaultEffect == Allow}
+}
+
+func (r *Rule) matches(request Request) bool {
+	if !matchAny(r.Tools, request.Tool, false) ||
+		!matchAny(r.Verbs, request.Verb, false) ||
+		!matchAny(r.Kinds, request.Kind, true) ||
+		!r.matchesNamespace(request) ||
+		!matchAny(r.Clusters, request.Cluster, false) ||
+		!matchAny(r.Users, request.User, false) {
+		return false
+	}
+	if len(r.Groups) == 0 {
+		return true
+	}
+	for _, group := range request.Groups {
+		if matchAny(r.Groups, group, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNamespace 涉及所有命名空间的请求也包括规则中的命名空间，所以限定了命名空间的deny规则总是匹配它；
+// 限定了命名空间的allow规则不匹配它，除非其中有匹配任何命名空间的 *
+func (r *Rule) matchesNamespace(request Request) bool {
+	if request.AllNamespaces && len(r.Namespaces) > 0 && r.Effect == Deny {
+		return true
+	}
+	return matchAny(r.Namespaces, request.Namespace, false)
+}
+
+// Explain 返回拒绝时给调用方的说明，指出是哪条规则拒绝了哪个操作
+func (d Decision) Explain(request Request) string {
+	target := request.Verb
+	if request.Kind != "" {
+		target += " " + request.Kind
+	}
+	if request.Namespace != "" {
+		target += " in namespace " + request.Namespace
+	} else if request.AllNamespaces {
+		target += " in all namespaces"
+	}
+	if request.Cluster != "" {
+		target += " of cluster " + request.Cluster
+	}
+	caller := request.User
+	if caller == "" {
+		caller = "anonymous caller"
+	}
+	var message string
+	if d.Rule == "" {
+		message = fmt.Sprintf("policy denied tool %s (%s) for %s: no rule matched and the default effect is deny", request.Tool, target, caller)
+	} else {
+		message = fmt.Sprintf("policy rule %q denied tool %s (%s) for %s", d.Rule, request.Tool, target, caller)
+	}
+	if d.Description != "" {
+		message += ": " + d.Description
+	}
+	return message
+}
+
+func matchAny(patterns []string, value string, foldCase bool) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if foldCase {
+			if matchGlob(strings.ToLower(pattern), strings.ToLower(value)) {
+				return true
+			}
+		} else if matchGlob(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob 和path.Match不同，* 也匹配 / 和 :，用户名（例如 system:serviceaccount:ns:name 或URL）中经常有这些字符
+func matchGlob(pattern, value string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(value); i++ {
+				if matchGlob(pattern, value[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if value == "" {
+				return false
+			}
+			pattern, value = pattern[1:], value[1:]
+		default:
+			if value == "" || pattern[0] != value[0] {
+				return false
+			}
+			pattern, value = pattern[1:], value[1:]
+		}
+	}
+	return value == ""
+}
+
+// DeniedError 是策略拒绝一次操作时返回的错误
+type DeniedError struct {
+	Decision Decision
+	Request  Request
+}
+
+func (e *DeniedError) Error() string {
+	return e.Decision.Explain(e.Request)
+}
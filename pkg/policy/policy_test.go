@@ -0,0 +1,93 @@
+package policy
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"default", "default", true},
+		{"default", "default2", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"kube-*", "kube-system", true},
+		{"kube-*", "kube-", true},
+		{"kube-*", "my-kube-system", false},
+		{"*-prod", "team-a-prod", true},
+		{"*-prod", "team-a-production", false},
+		{"team-*-prod", "team-a-prod", true},
+		{"team-*-prod", "team--prod", true},
+		{"team-*-prod", "team-prod", false},
+		{"?", "a", true},
+		{"?", "", false},
+		{"?", "ab", false},
+		{"ns-??", "ns-01", true},
+		{"ns-??", "ns-1", false},
+		{"a**b", "ab", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"system:serviceaccount:*", "system:serviceaccount:ci:deployer", true},
+		{"system:serviceaccount:ci:*", "system:serviceaccount:prod:deployer", false},
+		{"https://issuer.example.com/*", "https://issuer.example.com/users/alice", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policy := &Policy{
+		DefaultEffect: Deny,
+		Rules: []Rule{
+			{Name: "no-secrets", Effect: Deny, Kinds: []string{"secret"}, Description: "secrets are off limits"},
+			{Name: "admins", Effect: Allow, Groups: []string{"platform-*"}},
+			{Name: "protect-system", Effect: Deny, Verbs: []string{"delete", "apply"}, Namespaces: []string{"kube-*"}},
+			{Name: "no-new-namespaces", Effect: Deny, Verbs: []string{"apply"}, Kinds: []string{"Namespace"}},
+			{Name: "hide-system", Effect: Deny, Verbs: []string{"get", "list", "watch"}, Namespaces: []string{"kube-system"}},
+			{Name: "read-only", Effect: Allow, Verbs: []string{"get", "list"}},
+			{Name: "ci-deploys", Effect: Allow, Tools: []string{"applyManifests"}, Users: []string{"system:serviceaccount:ci:*"}, Clusters: []string{"staging"}},
+			{Name: "team-a-scales", Effect: Allow, Verbs: []string{"scale"}, Namespaces: []string{"team-a-*"}},
+		},
+	}
+	tests := []struct {
+		name     string
+		request  Request
+		wantRule string
+		allowed  bool
+	}{
+		{"first matching rule wins over a later allow", Request{Tool: "getResource", Verb: "get", Kind: "Secret", Groups: []string{"platform-admins"}}, "no-secrets", false},
+		{"kinds match case-insensitively", Request{Tool: "getResource", Verb: "get", Kind: "SECRET"}, "no-secrets", false},
+		{"any group may match", Request{Tool: "deleteResource", Verb: "delete", Kind: "Pod", Namespace: "kube-system", Groups: []string{"dev", "platform-admins"}}, "admins", true},
+		{"namespace glob", Request{Tool: "deleteResource", Verb: "delete", Kind: "Pod", Namespace: "kube-system"}, "protect-system", false},
+		{"namespace glob does not match other namespaces", Request{Tool: "deleteResource", Verb: "delete", Kind: "Pod", Namespace: "default"}, "", false},
+		{"namespace created by an apply", Request{Tool: "applyManifests", Verb: "apply", Kind: "Namespace", Cluster: "staging", User: "system:serviceaccount:ci:deployer"}, "no-new-namespaces", false},
+		{"read verbs", Request{Tool: "listResources", Verb: "list", Kind: "Deployment", Namespace: "default"}, "read-only", true},
+		{"all fields must match", Request{Tool: "applyManifests", Verb: "apply", Kind: "Deployment", Namespace: "default", Cluster: "staging", User: "system:serviceaccount:ci:deployer"}, "ci-deploys", true},
+		{"other cluster", Request{Tool: "applyManifests", Verb: "apply", Kind: "Deployment", Namespace: "default", Cluster: "prod", User: "system:serviceaccount:ci:deployer"}, "", false},
+		{"namespace deny rule covers all namespaces", Request{Tool: "listResources", Verb: "list", Kind: "ConfigMap", AllNamespaces: true}, "hide-system", false},
+		{"namespace deny rule covers watches of all namespaces", Request{Tool: "watchResources", Verb: "watch", Kind: "ConfigMap", AllNamespaces: true}, "hide-system", false},
+		{"namespace deny rule skips other namespaces", Request{Tool: "listResources", Verb: "list", Kind: "ConfigMap", Namespace: "default"}, "read-only", true},
+		{"namespace allow rule", Request{Tool: "scaleResource", Verb: "scale", Kind: "Deployment", Namespace: "team-a-web"}, "team-a-scales", true},
+		{"namespace allow rule does not cover all namespaces", Request{Tool: "scaleResource", Verb: "scale", Kind: "Deployment", AllNamespaces: true}, "", false},
+		{"rule with groups needs a group", Request{Tool: "scaleResource", Verb: "scale", Kind: "Deployment"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.Evaluate(tt.request)
+			if decision.Rule != tt.wantRule || decision.Allowed != tt.allowed {
+				t.Errorf("Evaluate() = rule %q allowed %v, want rule %q allowed %v", decision.Rule, decision.Allowed, tt.wantRule, tt.allowed)
+			}
+		})
+	}
+
+	policy.DefaultEffect = Allow
+	if decision := policy.Evaluate(Request{Tool: "scaleResource", Verb: "scale", Kind: "Deployment"}); !decision.Allowed || decision.Rule != "" {
+		t.Errorf("Evaluate() with default allow = rule %q allowed %v, want the default effect", decision.Rule, decision.Allowed)
+	}
+}
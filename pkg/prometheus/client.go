@@ -48,7 +48,7 @@ func (c *Client) QueryInstant(ctx context.Context, query string, ts time.Time) (
 		"query":      query,
 		"timestamp":  ts,
 		"warnings":   warnings,
-		"resultType": string(val.Type()),
+		"resultType": val.Type().String(),
 		"result":     convertPromModelValue(val),
 	}, nil
 }
@@ -69,7 +69,7 @@ func (c *Client) QueryRange(ctx context.Context, query string, start, end time.T
 		"end":        end,
 		"step":       step.String(),
 		"warnings":   warnings,
-		"resultType": string(val.Type()),
+		"resultType": val.Type().String(),
 		"result":     convertPromModelValue(val),
 	}, nil
 }
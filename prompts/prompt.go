@@ -5,6 +5,6 @@ import "github.com/mark3labs/mcp-go/mcp"
 func UseKindPrompt() mcp.Prompt {
 	return mcp.NewPrompt(
 		"arg-kind",
-		mcp.WithPromptDescription("When using the tools,make sure you pass parameters like kind=Pod, Deployment, Service, Ingress, etc."),
+		mcp.WithPromptDescription("How to pass the kind parameter: Kind, plural, singular or short names, optionally qualified with the API group"),
 	)
 }
@@ -10,3 +10,23 @@ func ManagerResource() mcp.Resource {
 		mcp.WithMIMEType("text/markdown"),
 	)
 }
+
+// ObjectResourceTemplate 是单个集群对象的资源模板，集群级别的对象namespace使用 "_"
+func ObjectResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(
+		"k8s://{cluster}/{namespace}/{kind}/{name}",
+		"Kubernetes Object",
+		mcp.WithTemplateDescription("A live Kubernetes object as JSON, e.g. k8s://default/kube-system/Deployment/coredns. Use \"_\" as namespace for cluster-scoped objects. Subscribe with subscribeResource to receive notifications/resources/updated when it changes"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+}
+
+// KindResourceTemplate 是默认集群中某个命名空间下某类对象列表的资源模板
+func KindResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(
+		"k8s://{namespace}/{kind}",
+		"Kubernetes Objects",
+		mcp.WithTemplateDescription("The objects of a kind in a namespace of the default cluster, e.g. k8s://default/Pod. Use \"_\" as namespace for all namespaces or cluster-scoped kinds. Each item has the uri of the object"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+}
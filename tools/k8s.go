@@ -2,6 +2,62 @@ package tools
 
 import "github.com/mark3labs/mcp-go/mcp"
 
+// withCluster 为Kubernetes工具添加可选的cluster参数
+func withCluster() mcp.ToolOption {
+	return mcp.WithString("cluster", mcp.Description("The cluster (kubeconfig context) to operate on, use listClusters to see the available ones. Defaults to the server's default cluster"))
+}
+
+// withProjection 为返回对象的工具添加fields、jsonPath和stripManaged参数
+func withProjection() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithArray("fields", mcp.WithStringItems(), mcp.Description("Only return these field paths, like kubectl custom-columns, e.g. [\"metadata.name\", \"spec.replicas\", \"status.conditions[*].type\"]"))(t)
+		mcp.WithString("jsonPath", mcp.Description("Render the result with a kubectl -o jsonpath template instead of returning JSON, e.g. {.status.phase} or {range .items[*]}{.metadata.name}{\"\\n\"}{end} for lists"))(t)
+		mcp.WithBoolean("stripManaged", mcp.Description("Remove metadata.managedFields and the last-applied-configuration annotation from full objects. Default is true"))(t)
+	}
+}
+
+// withApplyOptions 为使用server-side apply的工具添加dryRun、force和fieldManager参数
+func withApplyOptions() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithBoolean("dryRun", mcp.Description("Only compute the result and the diff on the server without persisting anything. Default is false"))(t)
+		mcp.WithBoolean("force", mcp.Description("Take ownership of fields that conflict with another field manager. Default is false"))(t)
+		mcp.WithString("fieldManager", mcp.Description("The field manager name used for server-side apply. Default is kube-mcp-server"))(t)
+	}
+}
+
+// withConfirmation 为需要确认的工具添加confirmationToken参数
+func withConfirmation() mcp.ToolOption {
+	return mcp.WithString("confirmationToken", mcp.Description("Only needed when the server requires confirmation of changes: the token returned by the first call, after the user approved its plan. Call again with exactly the same arguments plus this token"))
+}
+
+// withLogOptions 为读取日志的工具添加行数、时间范围、grep和字节预算等参数
+func withLogOptions() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithNumber("TailLogsLen", mcp.Description("The number of lines to read from the end of the log, 0 reads all of them. Default is 100, or all lines when grep is set"))(t)
+		mcp.WithBoolean("previous", mcp.Description("Read the logs of the previous, terminated container instead of the current one, needed to debug crashes. Default is false"))(t)
+		mcp.WithNumber("sinceSeconds", mcp.Description("Only return logs newer than this many seconds. Cannot be used with sinceTime"))(t)
+		mcp.WithString("sinceTime", mcp.Description("Only return logs after this RFC3339 time, e.g. 2024-01-02T15:04:05Z. Cannot be used with sinceSeconds"))(t)
+		mcp.WithBoolean("timestamps", mcp.Description("Prefix every line with its RFC3339 timestamp. Default is false"))(t)
+		mcp.WithString("grep", mcp.Description("Only return lines matching this regular expression (RE2 syntax), e.g. (?i)error|panic"))(t)
+		mcp.WithNumber("contextLines", mcp.Description("The number of lines to show before and after every grep match, like grep -C. Default is 0, at most 20"))(t)
+		mcp.WithNumber("maxBytes", mcp.Description("The byte budget of the returned logs, the oldest lines are dropped when it is exceeded. Default is 65536, at most 1048576"))(t)
+	}
+}
+
+func ListClustersTool() mcp.Tool {
+	return mcp.NewTool(
+		"listClusters",
+		mcp.WithDescription("List the Kubernetes clusters configured on this server, which one is the default, and whether each one is reachable"),
+	)
+}
+
+func WhoAmITool() mcp.Tool {
+	return mcp.NewTool(
+		"whoAmI",
+		mcp.WithDescription("Show the identity the caller authenticated as (sse and streamable-http mode) and the Kubernetes identity tool calls run as"),
+	)
+}
+
 func GetAPIResourcesTool() mcp.Tool {
 	return mcp.NewTool(
 		"getAPIResources",
@@ -15,6 +71,7 @@ func GetAPIResourcesTool() mcp.Tool {
 			"The function also handles the inclusion of namespace scoped\n"+
 			"and cluster scoped resources based on the provided parameters.\n"+
 			"The function is designed to be used as a handler for the mcp tool"),
+		withCluster(),
 		mcp.WithBoolean("includeNamespaceScoped", mcp.Description("Include namespace scoped resources")),
 		mcp.WithBoolean("includeClusterScoped", mcp.Description("Include cluster scoped resources")),
 	)
@@ -23,20 +80,26 @@ func GetAPIResourcesTool() mcp.Tool {
 func GetResourcesTool() mcp.Tool {
 	return mcp.NewTool(
 		"getResource",
-		mcp.WithDescription("Get a specific resource in the Kubernetes cluster，make sure use like Pod,Deployment,Service..."),
-		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to get,make sure use like Pod,Deployment,Service...")),
+		mcp.WithDescription("Get a specific resource in the Kubernetes cluster"),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to get, e.g. Pod, pods, po, deployments.apps or Deployment.v1.apps")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource to get")),
 		mcp.WithString("namespace", mcp.Description("The namespace of the resource,if in default namespace,use default")),
+		withProjection(),
 	)
 }
 func ListResourcesTool() mcp.Tool {
 	return mcp.NewTool(
 		"listResources",
-		mcp.WithDescription("List all resources in the Kubernetes cluster of a specific kind"),
-		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to list,make sure use like Pod ,Deployment....")),
+		mcp.WithDescription("List all resources in the Kubernetes cluster of a specific kind. Results are paginated, the response has the items, the total count and a continue token for the next page"),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to list, e.g. Pod, pods, po, deployments.apps or Deployment.v1.apps")),
 		mcp.WithString("namespace", mcp.Description("The namespace of the resources,if in default namespace,use default")),
 		mcp.WithString("labelSelector", mcp.Description("Label selector to filter resources")),
 		mcp.WithString("fieldSelector", mcp.Description("Field selector to filter resources")),
+		mcp.WithNumber("limit", mcp.Description("The maximum number of items to return, 0 returns all of them. Default is 200")),
+		mcp.WithString("continue", mcp.Description("The continue token returned by the previous page")),
+		withProjection(),
 	)
 }
 
@@ -44,10 +107,14 @@ func ListResourcesTool() mcp.Tool {
 func CreateOrUpdateResourceJSONTool() mcp.Tool {
 	return mcp.NewTool(
 		"createResourceJSON",
-		mcp.WithDescription("Create a resource in the Kubernetes cluster"),
-		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to create")),
-		mcp.WithString("namespace", mcp.Description("The namespace of the resource")),
-		mcp.WithString("manifest", mcp.Required(), mcp.Description("The manifest of the resource to create")),
+		mcp.WithDescription("Create or update a resource in the Kubernetes cluster from a JSON manifest using server-side apply. "+
+			"The result contains a diff between the live object and the object computed by the server. Use dryRun=true first to review the change before persisting it."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Description("The type of resource to create (optional, will be inferred from JSON manifest if not provided)")),
+		mcp.WithString("namespace", mcp.Description("The namespace of the resource (overrides namespace in JSON manifest if provided)")),
+		mcp.WithString("jsonManifest", mcp.Required(), mcp.Description("The JSON manifest of the resource to create or update")),
+		withApplyOptions(),
+		withConfirmation(),
 	)
 }
 
@@ -55,10 +122,30 @@ func CreateOrUpdateResourceJSONTool() mcp.Tool {
 func CreateOrUpdateResourceYAMLTool() mcp.Tool {
 	return mcp.NewTool(
 		"createResourceYAML",
-		mcp.WithDescription("Create or update a resource in the Kubernetes cluster from a YAML manifest. This tool is specifically optimized for YAML input and provides better error handling for YAML parsing issues."),
+		mcp.WithDescription("Create or update a resource in the Kubernetes cluster from a YAML manifest using server-side apply. This tool is specifically optimized for YAML input and provides better error handling for YAML parsing issues. "+
+			"The result contains a diff between the live object and the object computed by the server. Use dryRun=true first to review the change before persisting it."),
+		withCluster(),
 		mcp.WithString("kind", mcp.Description("The type of resource to create (optional, will be inferred from YAML manifest if not provided)")),
 		mcp.WithString("namespace", mcp.Description("The namespace of the resource (overrides namespace in YAML manifest if provided)")),
 		mcp.WithString("yamlManifest", mcp.Required(), mcp.Description("The YAML manifest of the resource to create or update. Must be valid Kubernetes YAML format.")),
+		withApplyOptions(),
+		withConfirmation(),
+	)
+}
+
+// ApplyManifestsTool creates a tool definition for applying multi-document YAML manifests
+func ApplyManifestsTool() mcp.Tool {
+	return mcp.NewTool(
+		"applyManifests",
+		mcp.WithDescription("Apply a multi-document YAML manifest (documents separated by ---) to the Kubernetes cluster using server-side apply. "+
+			"Objects are applied in dependency order: Namespaces and CRDs first, then config, RBAC and Services, workloads and Ingresses last. "+
+			"The result reports success or failure for every object. Use dryRun=true first to review the changes."),
+		withCluster(),
+		mcp.WithString("manifests", mcp.Required(), mcp.Description("The YAML manifests to apply, multiple documents separated by ---")),
+		mcp.WithString("namespace", mcp.Description("The namespace used for namespaced objects that do not specify one. Default is default")),
+		mcp.WithBoolean("continueOnError", mcp.Description("Keep applying the remaining objects after one fails. Default is false, which stops at the first error and skips the rest")),
+		withApplyOptions(),
+		withConfirmation(),
 	)
 }
 
@@ -66,30 +153,63 @@ func DeleteResourceTool() mcp.Tool {
 	return mcp.NewTool(
 		"deleteResource",
 		mcp.WithDescription("Delete a resource in the Kubernetes cluster"),
+		withCluster(),
 		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to delete")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource to delete")),
 		mcp.WithString("namespace", mcp.Description("The namespace of the resource")),
+		mcp.WithBoolean("dryRun", mcp.Description("Only validate the deletion on the server and return the object and its dependents that would be deleted. Default is false")),
+		withConfirmation(),
 	)
 }
 
 func DescribeResourcesTool() mcp.Tool {
 	return mcp.NewTool(
 		"describeResource",
-		mcp.WithDescription("Describe a resource in the Kubernetes cluster based on given kind and name"),
+		mcp.WithDescription("Describe a resource in the Kubernetes cluster based on given kind and name, like kubectl describe: a kind-specific summary, conditions, the owner chain, owned children with their status and related events sorted by time"),
+		withCluster(),
 		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to describe")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource to describe")),
 		mcp.WithString("namespace", mcp.Description("The namespace of the resource,if resource in default namespace,make sure use send default")),
+		withProjection(),
 	)
 }
 
 func GetPodsLogsTools() mcp.Tool {
 	return mcp.NewTool(
 		"getPodsLogs",
-		mcp.WithDescription("Get logs of a specific pod in the Kubernetes cluster"),
+		mcp.WithDescription("Get logs of a specific pod in the Kubernetes cluster. Supports the previous container, a time range, timestamps and a server-side grep with context lines; the output is limited by a byte budget that keeps the newest lines"),
+		withCluster(),
 		mcp.WithString("Name", mcp.Required(), mcp.Description("The name of the pod to get logs from")),
 		mcp.WithString("containerName", mcp.Description("The name of the container to get logs from")),
 		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the pod")),
-		mcp.WithNumber("TailLogsLen", mcp.Description("The number of lines in this log")),
+		withLogOptions(),
+	)
+}
+
+func GetWorkloadLogsTool() mcp.Tool {
+	return mcp.NewTool(
+		"getWorkloadLogs",
+		mcp.WithDescription("Get the logs of all pods of a workload (Deployment, StatefulSet, DaemonSet, Job, ...) or a label selector, like stern. "+
+			"Logs of all matching pods and containers are fetched concurrently and merged into one chronological stream, every line prefixed with [pod] or [pod/container]. "+
+			"TailLogsLen and the grep options apply to every container, maxBytes to the merged output"),
+		withCluster(),
+		mcp.WithString("kind", mcp.Description("The kind of the workload, e.g. Deployment, sts or Job. Requires name")),
+		mcp.WithString("name", mcp.Description("The name of the workload")),
+		mcp.WithString("labelSelector", mcp.Description("Label selector of the pods, e.g. app=web. Combined with the workload selector when kind and name are also given")),
+		mcp.WithString("namespace", mcp.Description("The namespace of the pods. Default is default")),
+		mcp.WithString("containerName", mcp.Description("Only read the containers with this name")),
+		withLogOptions(),
+	)
+}
+
+func DiagnosePodTool() mcp.Tool {
+	return mcp.NewTool(
+		"diagnosePod",
+		mcp.WithDescription("Troubleshoot a pod in one call. Classifies the failure (CrashLoopBackOff, ImagePullBackOff, OOMKilled, Unschedulable, ProbeFailure, InitContainerStuck, ContainerConfigError, VolumeMountFailure, NodeProblem, Evicted, StuckTerminating) "+
+			"and returns each finding with its likely causes and the evidence: last termination state, previous container logs, scheduler events, probe failures and node conditions"),
+		withCluster(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the pod to diagnose")),
+		mcp.WithString("namespace", mcp.Description("The namespace of the pod. Default is default")),
 	)
 }
 
@@ -97,6 +217,7 @@ func GetPodMetricsTool() mcp.Tool {
 	return mcp.NewTool(
 		"getPodMetrics",
 		mcp.WithDescription("Get CPU and Memory metrics for a specific pod"),
+		withCluster(),
 		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the pod")),
 		mcp.WithString("podName", mcp.Required(), mcp.Description("The name of the pod")),
 	)
@@ -106,7 +227,82 @@ func GetNodeMetricsTools() mcp.Tool {
 	return mcp.NewTool(
 		"getNodeMetrics",
 		mcp.WithDescription("Get resource usage of a specific node in the Kubernetes cluster"),
-		mcp.WithString("podName", mcp.Required(), mcp.Description("The name of the node to get resource usage from")),
+		withCluster(),
+		mcp.WithString("nodeName", mcp.Required(), mcp.Description("The name of the node to get resource usage from")),
+	)
+}
+
+// withTopOptions 为topPods和topNodes添加排序和数量参数
+func withTopOptions(percentOf string) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("labelSelector", mcp.Description("Label selector to filter the objects"))(t)
+		mcp.WithString("sortBy", mcp.Enum("cpu", "memory", "cpuPercent", "memoryPercent"), mcp.Description("Sort by usage or by usage as percent of "+percentOf+", highest first. Default is cpu"))(t)
+		mcp.WithNumber("limit", mcp.Description("Only return the top N items, 0 returns all of them. Default is 20"))(t)
+	}
+}
+
+func TopPodsTool() mcp.Tool {
+	return mcp.NewTool(
+		"topPods",
+		mcp.WithDescription("List the CPU and memory usage of pods from metrics-server, like kubectl top pods, joined with the pods' requests and limits and the usage as percent of them. "+
+			"Sorted and cut to the top N to keep the output small; total is the number of pods before the cutoff"),
+		withCluster(),
+		mcp.WithString("namespace", mcp.Description("The namespace of the pods, empty for all namespaces")),
+		withTopOptions("the limit (pods without a limit sort last)"),
+	)
+}
+
+func TopNodesTool() mcp.Tool {
+	return mcp.NewTool(
+		"topNodes",
+		mcp.WithDescription("List the CPU and memory usage of nodes from metrics-server, like kubectl top nodes, with the allocatable resources, the usage as percent of allocatable and the sum of the requests of the pods on each node"),
+		withCluster(),
+		withTopOptions("allocatable"),
+	)
+}
+
+func RightsizingReportTool() mcp.Tool {
+	return mcp.NewTool(
+		"rightsizingReport",
+		mcp.WithDescription("Compare the CPU and memory requests and limits of every container of the Deployments, StatefulSets and DaemonSets in a namespace with the observed p50, p95 and max usage, "+
+			"flag over-provisioned and under-provisioned containers and suggest values. Uses Prometheus history when Prometheus is enabled, otherwise a metrics-server snapshot of the current replicas"),
+		withCluster(),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the workloads")),
+		mcp.WithString("kind", mcp.Description("Only check workloads of this kind: Deployment, StatefulSet or DaemonSet")),
+		mcp.WithString("name", mcp.Description("Only check the workload with this name")),
+		mcp.WithString("window", mcp.Description("The usage history window when Prometheus is enabled, e.g. 24h or 7d. Default is 7d")),
+	)
+}
+
+// withAccessCheck 为canI和whoCan添加verb、kind、name和namespace参数
+func withAccessCheck() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("verb", mcp.Required(), mcp.Description("The verb to check, e.g. get, list, watch, create, update, patch, delete or *"))(t)
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The resource, e.g. pods, Deployment or deployments.apps, a subresource like pods/log or deployments/scale, * for all resources, or a non-resource URL starting with / like /healthz"))(t)
+		mcp.WithString("name", mcp.Description("The name of a specific object"))(t)
+		mcp.WithString("namespace", mcp.Description("The namespace, ignored for cluster scoped resources"))(t)
+	}
+}
+
+func CanITool() mcp.Tool {
+	return mcp.NewTool(
+		"canI",
+		mcp.WithDescription("Check whether the server's identity, or another user, may perform a verb on a resource, like kubectl auth can-i. "+
+			"Use it before a write to avoid a Forbidden error. Without user and groups it uses a SelfSubjectAccessReview, otherwise a SubjectAccessReview"),
+		withCluster(),
+		withAccessCheck(),
+		mcp.WithString("user", mcp.Description("Check the permissions of this user or service account (system:serviceaccount:<namespace>:<name>) instead of the server's identity")),
+		mcp.WithArray("groups", mcp.WithStringItems(), mcp.Description("The groups of the user to check")),
+	)
+}
+
+func WhoCanTool() mcp.Tool {
+	return mcp.NewTool(
+		"whoCan",
+		mcp.WithDescription("List the users, groups and service accounts that RBAC allows to perform a verb on a resource, with the binding and role that grant it. "+
+			"Walks ClusterRoleBindings and, for namespaced resources, the RoleBindings of the namespace (all namespaces when it is empty)"),
+		withCluster(),
+		withAccessCheck(),
 	)
 }
 
@@ -114,6 +310,7 @@ func GetEventsTools() mcp.Tool {
 	return mcp.NewTool(
 		"getEvents",
 		mcp.WithDescription("Get events in the Kubernetes cluster"),
+		withCluster(),
 		mcp.WithString("namespace", mcp.Description("The namespace to get events from")),
 		mcp.WithString("labelSelector", mcp.Description("A label selector to filter events")),
 	)
@@ -123,6 +320,7 @@ func GetIngressesTool() mcp.Tool {
 	return mcp.NewTool(
 		"getIngresses",
 		mcp.WithDescription("Get ingresses in the Kubernetes cluster"),
+		withCluster(),
 		mcp.WithString("host", mcp.Required(), mcp.Description("The host to get ingresses from")),
 	)
 }
@@ -132,8 +330,184 @@ func RolloutRestartTool() mcp.Tool {
 	return mcp.NewTool(
 		"rolloutRestart",
 		mcp.WithDescription("Perform a rollout restart on a Deployment, DaemonSet, StatefulSet, ReplicaSet, or any resource with spec.template."),
+		withCluster(),
 		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to restart (e.g., Deployment, DaemonSet)")),
 		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource")),
 		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the resource")),
+		mcp.WithBoolean("dryRun", mcp.Description("Only return the patched object computed by the server without restarting anything. Default is false")),
+		withConfirmation(),
+	)
+}
+
+// ScaleResourceTool creates a tool for changing the replica count of any kind with a scale subresource.
+func ScaleResourceTool() mcp.Tool {
+	return mcp.NewTool(
+		"scaleResource",
+		mcp.WithDescription("Scale a Deployment, StatefulSet, ReplicaSet, or any custom resource that exposes the scale subresource (e.g. Argo Rollouts). Returns the replica count before and after."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to scale (e.g., Deployment, StatefulSet)")),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource")),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the resource")),
+		mcp.WithString("replicas", mcp.Required(), mcp.Description("An absolute replica count like 3, or a relative change like +2 or -1")),
+		mcp.WithBoolean("wait", mcp.Description("Wait until the ready replicas converge to the desired count. Default is false")),
+		mcp.WithNumber("timeoutSeconds", mcp.Description("How long to wait for the ready replicas when wait is true. Default is 300")),
+	)
+}
+
+// RolloutStatusTool creates a tool for watching the progress of a rollout.
+func RolloutStatusTool() mcp.Tool {
+	return mcp.NewTool(
+		"rolloutStatus",
+		mcp.WithDescription("Get the rollout status of a Deployment, StatefulSet or DaemonSet. Waits until the rollout finishes, fails or the timeout expires, and reports updated, ready and available counts and failing conditions."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource: Deployment, StatefulSet or DaemonSet")),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource")),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the resource")),
+		mcp.WithNumber("timeoutSeconds", mcp.Description("How long to wait for the rollout to finish, 0 returns the current status immediately. Default is 60")),
+	)
+}
+
+// RolloutHistoryTool creates a tool for listing the revisions of a workload.
+func RolloutHistoryTool() mcp.Tool {
+	return mcp.NewTool(
+		"rolloutHistory",
+		mcp.WithDescription("List the revisions of a Deployment, StatefulSet or DaemonSet with their change-cause and the pod template diff against the previous revision."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource: Deployment, StatefulSet or DaemonSet")),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource")),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the resource")),
+		mcp.WithNumber("revision", mcp.Description("Also return the full pod template of this revision")),
+	)
+}
+
+// RolloutUndoTool creates a tool for rolling a workload back to an earlier revision.
+func RolloutUndoTool() mcp.Tool {
+	return mcp.NewTool(
+		"rolloutUndo",
+		mcp.WithDescription("Roll back a Deployment, StatefulSet or DaemonSet to an earlier revision. Use rolloutHistory to find the revision."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource: Deployment, StatefulSet or DaemonSet")),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the resource")),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the resource")),
+		mcp.WithNumber("toRevision", mcp.Description("The revision to roll back to. Default is the previous revision")),
+	)
+}
+
+// ExecInPodTool creates a tool for running allowlisted diagnostic commands inside a container.
+func ExecInPodTool() mcp.Tool {
+	return mcp.NewTool(
+		"execInPod",
+		mcp.WithDescription("Run a diagnostic command inside a container of a pod, e.g. [\"cat\", \"/etc/resolv.conf\"], [\"ps\", \"aux\"] or [\"nslookup\", \"kubernetes.default\"]. "+
+			"The command is executed directly without a shell and must be one of the commands allowed by the server, with exactly the same arguments unless the allowed command ends with *. Returns stdout, stderr and the exit code."),
+		withCluster(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the pod")),
+		mcp.WithString("namespace", mcp.Required(), mcp.Description("The namespace of the pod")),
+		mcp.WithArray("command", mcp.Required(), mcp.WithStringItems(), mcp.Description("The command and its arguments, one array element per argument")),
+		mcp.WithString("containerName", mcp.Description("The container to run the command in. Default is the first container of the pod")),
+		mcp.WithNumber("timeoutSeconds", mcp.Description("Abort the command after this many seconds. Default is 30, maximum is 300")),
+	)
+}
+
+// WatchResourcesTool creates a tool for subscribing to changes of Kubernetes resources.
+func WatchResourcesTool() mcp.Tool {
+	return mcp.NewTool(
+		"watchResources",
+		mcp.WithDescription("Subscribe to changes of a resource kind. Every add, update (with a compact field diff) and delete of matching objects, and Warning events about them, are pushed to this session as notifications/message with logger \"kubernetes\" until unwatchResources is called or the session ends."),
+		withCluster(),
+		mcp.WithString("kind", mcp.Required(), mcp.Description("The type of resource to watch, e.g. Pod or Deployment")),
+		mcp.WithString("namespace", mcp.Description("Only watch objects in this namespace. Default watches all namespaces")),
+		mcp.WithString("labelSelector", mcp.Description("Only watch objects matching this label selector, e.g. app=nginx")),
+		mcp.WithBoolean("warningEvents", mcp.Description("Also push Warning events about the watched objects. Default is true")),
+	)
+}
+
+// UnwatchResourcesTool creates a tool for cancelling a watch.
+func UnwatchResourcesTool() mcp.Tool {
+	return mcp.NewTool(
+		"unwatchResources",
+		mcp.WithDescription("Cancel a watch created by watchResources. Cancels all watches of this session when watchId is omitted."),
+		mcp.WithString("watchId", mcp.Description("The id returned by watchResources")),
+	)
+}
+
+// ListWatchesTool creates a tool for listing the watches of the current session.
+func ListWatchesTool() mcp.Tool {
+	return mcp.NewTool(
+		"listWatches",
+		mcp.WithDescription("List the active watches of this session."),
+	)
+}
+
+// SubscribeResourceTool creates a tool for subscribing to a k8s:// resource.
+func SubscribeResourceTool() mcp.Tool {
+	return mcp.NewTool(
+		"subscribeResource",
+		mcp.WithDescription("Subscribe to a k8s:// resource, e.g. k8s://default/kube-system/Deployment/coredns or k8s://default/Pod. notifications/resources/updated is sent to this session whenever the object (or any object of the list) changes; read the resource again to get the new content."),
+		mcp.WithString("uri", mcp.Required(), mcp.Description("The resource uri, k8s://{cluster}/{namespace}/{kind}/{name} or k8s://{namespace}/{kind}")),
+	)
+}
+
+// UnsubscribeResourceTool creates a tool for cancelling a resource subscription.
+func UnsubscribeResourceTool() mcp.Tool {
+	return mcp.NewTool(
+		"unsubscribeResource",
+		mcp.WithDescription("Cancel a subscription created by subscribeResource."),
+		mcp.WithString("uri", mcp.Required(), mcp.Description("The subscribed resource uri")),
+	)
+}
+
+// QueryAuditLogTool creates a tool for searching the audit log of tool calls.
+func QueryAuditLogTool() mcp.Tool {
+	return mcp.NewTool(
+		"queryAuditLog",
+		mcp.WithDescription("Search the audit log of tool calls, newest first, e.g. what was changed today, who deleted a Deployment or which calls were denied by the policy. "+
+			"Each entry has the time, session, caller, tool, redacted arguments, target objects, outcome, duration and the resulting resourceVersions."),
+		mcp.WithString("since", mcp.Description("Only return calls at or after this time: today, a duration back from now like 1h or 7d, a date like 2024-05-01 or an RFC3339 time. Default is today")),
+		mcp.WithString("until", mcp.Description("Only return calls before this time, same formats as since")),
+		mcp.WithString("tool", mcp.Description("Only return calls of this tool, e.g. deleteResource")),
+		mcp.WithString("user", mcp.Description("Only return calls made by this user")),
+		mcp.WithString("session", mcp.Description("Only return calls made in this MCP session")),
+		mcp.WithString("outcome", mcp.Description("Only return calls with this outcome"), mcp.Enum("success", "error", "denied", "planned")),
+		mcp.WithString("cluster", mcp.Description("Only return calls that targeted this cluster")),
+		mcp.WithString("kind", mcp.Description("Only return calls that targeted this kind of resource")),
+		mcp.WithString("name", mcp.Description("Only return calls that targeted an object with this name")),
+		mcp.WithString("namespace", mcp.Description("Only return calls that targeted this namespace")),
+		mcp.WithBoolean("changesOnly", mcp.Description("Only return calls of tools that change something. Default is true")),
+		mcp.WithNumber("limit", mcp.Description("Maximum number of entries to return. Default is 50")),
+	)
+}
+
+// CordonNodeTool creates a tool for marking a node unschedulable.
+func CordonNodeTool() mcp.Tool {
+	return mcp.NewTool(
+		"cordonNode",
+		mcp.WithDescription("Mark a node as unschedulable so that no new pods are scheduled on it. Existing pods keep running."),
+		withCluster(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the node")),
+	)
+}
+
+// UncordonNodeTool creates a tool for marking a node schedulable again.
+func UncordonNodeTool() mcp.Tool {
+	return mcp.NewTool(
+		"uncordonNode",
+		mcp.WithDescription("Mark a node as schedulable again."),
+		withCluster(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the node")),
+	)
+}
+
+// DrainNodeTool creates a tool for draining a node with the Eviction API.
+func DrainNodeTool() mcp.Tool {
+	return mcp.NewTool(
+		"drainNode",
+		mcp.WithDescription("Cordon a node and evict its pods with the Eviction API, respecting PodDisruptionBudgets. DaemonSet pods and mirror pods are skipped. Use dryRun first to see which pods would be evicted and which PodDisruptionBudgets currently block them. Progress is reported per evicted pod when the request has a progress token."),
+		withCluster(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("The name of the node")),
+		mcp.WithBoolean("dryRun", mcp.Description("Only list the pods that would be evicted or skipped, without cordoning or evicting. Default is false")),
+		mcp.WithBoolean("deleteEmptyDirData", mcp.Description("Also evict pods using emptyDir volumes, their data is lost. Default is false")),
+		mcp.WithBoolean("force", mcp.Description("Also evict pods that are not managed by a controller, they are not recreated. Default is false")),
+		mcp.WithNumber("gracePeriodSeconds", mcp.Description("Grace period for the evicted pods, negative uses the pod's own terminationGracePeriodSeconds. Default is -1")),
+		mcp.WithNumber("timeoutSeconds", mcp.Description("How long to keep evicting (retrying pods blocked by PodDisruptionBudgets) and waiting for the pods to be deleted. Default is 300, must be positive, maximum is 3600")),
 	)
 }